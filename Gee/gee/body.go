@@ -0,0 +1,165 @@
+package gee
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"mime/multipart"
+	"net/http"
+)
+
+// BodyLimit 设置分组内请求体的最大字节数，覆盖Engine.MaxBodyBytes；n小于0表示不限制。
+// 多个分组都包含请求路径时，使用前缀最长的分组的设置。需要单独限制某个路由时使用RouteBodyLimit。
+func (group *RouteGroup) BodyLimit(n int64) {
+	group.bodyLimit = n
+}
+
+// RouteBodyLimit 设置单个路由的请求体最大字节数，优先于分组和Engine的设置；n小于0表示不限制，为0时取消设置。
+// method和pattern与注册路由时相同，pattern相对于分组前缀：
+//
+//	r.POST("/upload", handler)
+//	r.RouteBodyLimit("POST", "/upload", 100<<20)
+func (group *RouteGroup) RouteBodyLimit(method, pattern string, n int64) {
+	engine := group.engine
+	if engine.bodyLimits == nil {
+		engine.bodyLimits = make(map[string]int64)
+	}
+	key := method + "-" + group.prefix + pattern
+	if n == 0 {
+		delete(engine.bodyLimits, key)
+		return
+	}
+	engine.bodyLimits[key] = n
+}
+
+// maxBodyBytes 返回请求适用的请求体大小限制，小于等于0表示不限制。
+// routeKey为路由查找时匹配到的路由键，依次使用路由、前缀最长的分组和引擎（包括子引擎所属的引擎）的设置。
+func (engine *Engine) maxBodyBytes(routeKey, path string) int64 {
+	if limit, ok := engine.bodyLimits[routeKey]; ok {
+		return limit
+	}
+	var best *RouteGroup
+	for _, group := range engine.groups {
+		if group.bodyLimit == 0 || !group.inGroup(path) {
+			continue
+		}
+		if best == nil || len(group.prefix) > len(best.prefix) {
+			best = group
+		}
+	}
+	if best != nil {
+		return best.bodyLimit
+	}
+	// 通过Host创建的子引擎没有设置时使用所属引擎的限制
+	for ; engine != nil; engine = engine.parent {
+		if engine.MaxBodyBytes != 0 {
+			return engine.MaxBodyBytes
+		}
+	}
+	return 0
+}
+
+// IsBodyTooLarge 判断读取请求体时的错误是否因为超出了大小限制。
+func IsBodyTooLarge(err error) bool {
+	var mbe *http.MaxBytesError
+	return errors.As(err, &mbe)
+}
+
+// limitBody 为请求体设置大小限制。Content-Length已经超出限制时返回false，请求不应再交给处理函数。
+func (c *Context) limitBody(w http.ResponseWriter, limit int64) bool {
+	req := c.Req
+	if limit <= 0 || req.Body == nil || req.Body == http.NoBody {
+		return true
+	}
+	if req.ContentLength > limit {
+		return false
+	}
+	req.Body = &limitedBody{ReadCloser: http.MaxBytesReader(w, req.Body, limit), c: c}
+	return true
+}
+
+// limitedBody 在请求体超出限制时做记录，处理函数没有写出响应时由引擎返回413。
+type limitedBody struct {
+	io.ReadCloser
+	c *Context
+}
+
+func (b *limitedBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if err != nil && IsBodyTooLarge(err) {
+		b.c.bodyTooLarge = true
+	}
+	return n, err
+}
+
+// entityTooLarge 返回413响应并终止处理链。
+func entityTooLarge(c *Context) {
+	c.Fail(http.StatusRequestEntityTooLarge, http.StatusText(http.StatusRequestEntityTooLarge))
+}
+
+// GetRawData 读取完整的请求体。读取的内容会被缓存，并重新设置c.Req.Body，
+// 因此中间件和处理函数可以多次调用GetRawData，之后仍能通过c.Req.Body、PostForm等读取请求体。
+// 请求体超出大小限制时返回的错误可以用IsBodyTooLarge判断。
+func (c *Context) GetRawData() ([]byte, error) {
+	if !c.rawRead {
+		if c.Req.Body != nil {
+			data, err := io.ReadAll(c.Req.Body)
+			if err != nil {
+				return nil, err
+			}
+			c.rawData = data
+		}
+		c.rawRead = true
+	}
+	c.Req.Body = io.NopCloser(bytes.NewReader(c.rawData))
+	return c.rawData, nil
+}
+
+// StreamBody 以不超过size字节的块逐段读取请求体并交给fn处理，适合处理分块传输的大文件上传，
+// 不会把整个请求体读入内存。size小于等于0时使用32KB。fn返回错误时停止读取并返回该错误。
+func (c *Context) StreamBody(size int, fn func(chunk []byte) error) error {
+	if c.Req.Body == nil {
+		return nil
+	}
+	if size <= 0 {
+		size = 32 << 10
+	}
+	buf := make([]byte, size)
+	for {
+		n, err := c.Req.Body.Read(buf)
+		if n > 0 {
+			if ferr := fn(buf[:n]); ferr != nil {
+				return ferr
+			}
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// StreamMultipart 逐个读取multipart/form-data请求中的各个部分并交给fn处理，
+// 文件内容不会被写入内存或临时文件。fn返回错误时停止读取并返回该错误。
+func (c *Context) StreamMultipart(fn func(part *multipart.Part) error) error {
+	reader, err := c.Req.MultipartReader()
+	if err != nil {
+		return err
+	}
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		err = fn(part)
+		part.Close()
+		if err != nil {
+			return err
+		}
+	}
+}
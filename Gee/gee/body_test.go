@@ -0,0 +1,183 @@
+package gee
+
+import (
+	"bytes"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// unsizedReader 隐藏底层Reader的类型，使httptest.NewRequest无法得知Content-Length，模拟分块传输的请求体。
+type unsizedReader struct{ r io.Reader }
+
+func (u unsizedReader) Read(p []byte) (int, error) { return u.r.Read(p) }
+
+// echoBody 返回读取到的请求体长度，超出限制时不写出响应，由引擎返回413。
+func echoBody(c *Context) {
+	data, err := c.GetRawData()
+	if err != nil {
+		if !IsBodyTooLarge(err) {
+			c.Fail(http.StatusBadRequest, err.Error())
+		}
+		return
+	}
+	c.String(http.StatusOK, "%d", len(data))
+}
+
+// sendBody 发送n字节的请求体，chunked为true时不设置Content-Length。
+func sendBody(r http.Handler, method, path string, n int, chunked bool) *httptest.ResponseRecorder {
+	var body io.Reader = strings.NewReader(strings.Repeat("x", n))
+	if chunked {
+		body = unsizedReader{body}
+	}
+	return performRequest(r, method, path, body)
+}
+
+func TestBodyLimits(t *testing.T) {
+	r := New()
+	r.MaxBodyBytes = 10
+	r.POST("/echo", echoBody)
+	r.POST("/avatar", echoBody)
+	r.RouteBodyLimit(http.MethodPost, "/avatar", 20)
+	r.Handle(http.MethodPut, "/avatar", echoBody)
+
+	up := r.Group("/up")
+	up.BodyLimit(-1)
+	up.POST("/any", echoBody)
+	up.POST("/small", echoBody)
+	up.RouteBodyLimit(http.MethodPost, "/small", 5)
+	up.POST("/users/:id", echoBody)
+	up.RouteBodyLimit(http.MethodPost, "/users/:id", 3)
+	up.POST("/reset", echoBody)
+	up.RouteBodyLimit(http.MethodPost, "/reset", 5)
+	up.RouteBodyLimit(http.MethodPost, "/reset", 0)
+
+	tests := []struct {
+		path    string
+		method  string
+		size    int
+		code    int
+		chunked bool
+	}{
+		{"/echo", http.MethodPost, 10, http.StatusOK, false},
+		{"/echo", http.MethodPost, 11, http.StatusRequestEntityTooLarge, false},
+		{"/echo", http.MethodPost, 11, http.StatusRequestEntityTooLarge, true},
+		{"/avatar", http.MethodPost, 20, http.StatusOK, false},
+		{"/avatar", http.MethodPost, 21, http.StatusRequestEntityTooLarge, true},
+		{"/avatar", http.MethodPut, 11, http.StatusRequestEntityTooLarge, false},
+		{"/up/any", http.MethodPost, 1000, http.StatusOK, true},
+		{"/up/small", http.MethodPost, 6, http.StatusRequestEntityTooLarge, false},
+		{"/up/users/7", http.MethodPost, 3, http.StatusOK, false},
+		{"/up/users/7", http.MethodPost, 4, http.StatusRequestEntityTooLarge, true},
+		{"/up/reset", http.MethodPost, 1000, http.StatusOK, false},
+	}
+	for _, tt := range tests {
+		w := sendBody(r, tt.method, tt.path, tt.size, tt.chunked)
+		if w.Code != tt.code {
+			t.Errorf("%s %s with %d bytes (chunked %v) = %d %q, want %d", tt.method, tt.path, tt.size, tt.chunked, w.Code, w.Body, tt.code)
+		}
+	}
+}
+
+func TestBodyLimitInHostEngine(t *testing.T) {
+	r := New()
+	r.MaxBodyBytes = 4
+	api := r.Host("api.example.com")
+	api.POST("/echo", echoBody)
+	api.POST("/big", echoBody)
+	api.RouteBodyLimit(http.MethodPost, "/big", 8)
+	files := r.Host("files.example.com")
+	files.MaxBodyBytes = -1
+	files.POST("/upload", echoBody)
+
+	do := func(host, path string, n int) int {
+		req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(strings.Repeat("x", n)))
+		req.Host = host
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+	if code := do("api.example.com", "/echo", 5); code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("inherited limit = %d", code)
+	}
+	if code := do("api.example.com", "/big", 8); code != http.StatusOK {
+		t.Fatalf("route limit in host engine = %d", code)
+	}
+	if code := do("files.example.com", "/upload", 100); code != http.StatusOK {
+		t.Fatalf("unlimited host engine = %d", code)
+	}
+}
+
+func TestGetRawDataRereadable(t *testing.T) {
+	r := New()
+	r.Use(func(c *Context) {
+		c.GetRawData()
+		c.Next()
+	})
+	r.POST("/echo", func(c *Context) {
+		first, _ := c.GetRawData()
+		rest, _ := io.ReadAll(c.Req.Body)
+		c.String(http.StatusOK, "%s|%s", first, rest)
+	})
+	if w := performRequest(r, http.MethodPost, "/echo", strings.NewReader("hello")); w.Body.String() != "hello|hello" {
+		t.Fatalf("body = %q", w.Body)
+	}
+}
+
+func TestStreamBody(t *testing.T) {
+	r := New()
+	r.POST("/stream", func(c *Context) {
+		var chunks []int
+		total := 0
+		err := c.StreamBody(4, func(chunk []byte) error {
+			chunks = append(chunks, len(chunk))
+			total += len(chunk)
+			return nil
+		})
+		c.String(http.StatusOK, "%d %v %v", total, len(chunks) >= 3, err)
+	})
+	r.POST("/stop", func(c *Context) {
+		err := c.StreamBody(0, func(chunk []byte) error { return io.ErrShortWrite })
+		c.String(http.StatusOK, "%v", err)
+	})
+	if w := sendBody(r, http.MethodPost, "/stream", 10, true); w.Body.String() != "10 true <nil>" {
+		t.Fatalf("StreamBody = %q", w.Body)
+	}
+	if w := sendBody(r, http.MethodPost, "/stop", 10, false); w.Body.String() != io.ErrShortWrite.Error() {
+		t.Fatalf("StreamBody error = %q", w.Body)
+	}
+}
+
+func TestStreamMultipart(t *testing.T) {
+	var buf bytes.Buffer
+	mw := multipart.NewWriter(&buf)
+	mw.WriteField("title", "notes")
+	fw, _ := mw.CreateFormFile("file", "a.txt")
+	fw.Write([]byte("data"))
+	mw.Close()
+
+	r := New()
+	r.POST("/upload", func(c *Context) {
+		var parts []string
+		err := c.StreamMultipart(func(part *multipart.Part) error {
+			data, err := io.ReadAll(part)
+			parts = append(parts, part.FormName()+"="+string(data))
+			return err
+		})
+		if err != nil {
+			c.Fail(http.StatusBadRequest, err.Error())
+			return
+		}
+		c.String(http.StatusOK, strings.Join(parts, ","))
+	})
+	w := performRequest(r, http.MethodPost, "/upload", &buf, "Content-Type", mw.FormDataContentType())
+	if w.Body.String() != "title=notes,file=data" {
+		t.Fatalf("StreamMultipart = %d %q", w.Code, w.Body)
+	}
+	if w := performRequest(r, http.MethodPost, "/upload", strings.NewReader("x")); w.Code != http.StatusBadRequest {
+		t.Fatalf("non-multipart request = %d", w.Code)
+	}
+}
@@ -0,0 +1,187 @@
+package gee
+
+import (
+	"fmt"
+	"net"
+	"net/netip"
+	"strings"
+)
+
+// 常见平台设置的客户端IP请求头，可以赋值给Engine.TrustedPlatform。
+const (
+	// PlatformCloudflare 是Cloudflare设置的请求头。
+	PlatformCloudflare = "CF-Connecting-IP"
+	// PlatformGoogleAppEngine 是Google App Engine设置的请求头。
+	PlatformGoogleAppEngine = "X-Appengine-Remote-Addr"
+	// PlatformFlyIO 是Fly.io设置的请求头。
+	PlatformFlyIO = "Fly-Client-IP"
+)
+
+// defaultRemoteIPHeaders 是New创建的引擎默认信任的请求头。
+var defaultRemoteIPHeaders = []string{"X-Forwarded-For", "X-Real-IP"}
+
+// SetTrustedProxies 设置可信的代理，元素可以是IP地址或CIDR，例如 "10.0.0.0/8"、"::1"。
+// 只有直接连接的对端位于可信代理中时，ClientIP才会读取RemoteIPHeaders中的请求头；
+// 默认不信任任何代理。传入nil清空设置。任何一个元素格式错误时返回错误，原有设置保持不变。
+func (engine *Engine) SetTrustedProxies(proxies []string) error {
+	prefixes := make([]netip.Prefix, 0, len(proxies))
+	for _, p := range proxies {
+		prefix, err := parsePrefix(p)
+		if err != nil {
+			return fmt.Errorf("gee: invalid trusted proxy %q: %w", p, err)
+		}
+		prefixes = append(prefixes, prefix)
+	}
+	engine.trustedProxies = prefixes
+	return nil
+}
+
+// parsePrefix 解析IP地址或CIDR，单个地址视为只包含它自己的网段。
+func parsePrefix(s string) (netip.Prefix, error) {
+	s = strings.TrimSpace(s)
+	if strings.Contains(s, "/") {
+		prefix, err := netip.ParsePrefix(s)
+		if err != nil {
+			return netip.Prefix{}, err
+		}
+		if prefix.Addr().Is4In6() {
+			bits := prefix.Bits() - 96
+			if bits < 0 {
+				return netip.Prefix{}, fmt.Errorf("prefix length too short for an IPv4-mapped address")
+			}
+			prefix = netip.PrefixFrom(prefix.Addr().Unmap(), bits)
+		}
+		return prefix.Masked(), nil
+	}
+	addr, err := netip.ParseAddr(s)
+	if err != nil {
+		return netip.Prefix{}, err
+	}
+	addr = addr.Unmap()
+	return netip.PrefixFrom(addr, addr.BitLen()), nil
+}
+
+// isTrustedProxy 判断地址是否位于可信代理中。
+func (engine *Engine) isTrustedProxy(addr netip.Addr) bool {
+	for _, prefix := range engine.trustedProxies {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// RemoteIP 返回直接连接的对端IP地址（来自Request.RemoteAddr），无法解析时返回空字符串，
+// 例如通过Unix套接字连接时。
+func (c *Context) RemoteIP() string {
+	if addr, ok := c.remoteAddr(); ok {
+		return addr.String()
+	}
+	return ""
+}
+
+func (c *Context) remoteAddr() (netip.Addr, bool) {
+	host, _, err := net.SplitHostPort(strings.TrimSpace(c.Req.RemoteAddr))
+	if err != nil {
+		host = c.Req.RemoteAddr
+	}
+	return parseIP(host)
+}
+
+// parseIP 解析IP地址，IPv4映射的IPv6地址转换为IPv4，忽略IPv6的zone。
+func parseIP(s string) (netip.Addr, bool) {
+	addr, err := netip.ParseAddr(strings.TrimSpace(s))
+	if err != nil {
+		return netip.Addr{}, false
+	}
+	return addr.Unmap().WithZone(""), true
+}
+
+// ClientIP 返回客户端的真实IP地址，结果在Context中缓存：
+//  1. 设置了Engine.TrustedPlatform且该请求头是合法IP时，直接使用它；
+//  2. 直接连接的对端不是可信代理时，返回RemoteIP；
+//  3. 否则依次检查RemoteIPHeaders。X-Forwarded-For和Forwarded从右向左遍历，
+//     跳过可信代理，返回第一个不可信的地址；全部可信时返回最左边的地址。
+//     X-Real-IP等其他请求头只包含一个地址；
+//  4. 请求头都不可用时返回RemoteIP。
+func (c *Context) ClientIP() string {
+	if c.clientIP == "" {
+		c.clientIP = c.resolveClientIP()
+	}
+	return c.clientIP
+}
+
+func (c *Context) resolveClientIP() string {
+	if c.engine == nil {
+		return c.RemoteIP()
+	}
+	engine := c.engine.root()
+	if engine.TrustedPlatform != "" {
+		if addr, ok := parseIP(c.Req.Header.Get(engine.TrustedPlatform)); ok {
+			return addr.String()
+		}
+	}
+	remote, ok := c.remoteAddr()
+	if !ok || !engine.isTrustedProxy(remote) {
+		return c.RemoteIP()
+	}
+	for _, name := range engine.RemoteIPHeaders {
+		var chain []string
+		switch strings.ToLower(name) {
+		case "x-forwarded-for":
+			for _, v := range c.Req.Header.Values(name) {
+				chain = append(chain, strings.Split(v, ",")...)
+			}
+		case "forwarded":
+			chain = forwardedFor(c.Req.Header.Values(name))
+		default:
+			chain = []string{c.Req.Header.Get(name)}
+		}
+		if addr, ok := engine.walkChain(chain); ok {
+			return addr.String()
+		}
+	}
+	return remote.String()
+}
+
+// walkChain 从右向左遍历代理链，返回第一个不是可信代理的地址，全部可信时返回最左边的地址。
+// 遇到无法解析的地址时说明该请求头不可信，返回false。
+func (engine *Engine) walkChain(chain []string) (netip.Addr, bool) {
+	var addr netip.Addr
+	for i := len(chain) - 1; i >= 0; i-- {
+		var ok bool
+		if addr, ok = parseIP(chain[i]); !ok {
+			return netip.Addr{}, false
+		}
+		if !engine.isTrustedProxy(addr) {
+			return addr, true
+		}
+	}
+	return addr, addr.IsValid()
+}
+
+// forwardedFor 从RFC 7239 Forwarded请求头中按顺序取出各个for参数的地址，去掉引号、方括号和端口。
+// 混淆的标识（例如 "unknown"、"_hidden"）原样保留，解析时会被视为无效地址。
+func forwardedFor(values []string) []string {
+	var chain []string
+	for _, v := range values {
+		for _, element := range strings.Split(v, ",") {
+			for _, pair := range strings.Split(element, ";") {
+				key, value, ok := strings.Cut(strings.TrimSpace(pair), "=")
+				if !ok || !strings.EqualFold(strings.TrimSpace(key), "for") {
+					continue
+				}
+				value = strings.Trim(strings.TrimSpace(value), `"`)
+				if strings.HasPrefix(value, "[") {
+					if end := strings.IndexByte(value, ']'); end > 0 {
+						value = value[1:end]
+					}
+				} else if host, _, err := net.SplitHostPort(value); err == nil {
+					value = host
+				}
+				chain = append(chain, value)
+			}
+		}
+	}
+	return chain
+}
@@ -0,0 +1,119 @@
+package gee
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+// ipRequest 以remote为对端地址请求/ip，headers依次为请求头的名称和值。
+func ipRequest(r http.Handler, host, remote string, headers ...string) string {
+	req := httptest.NewRequest(http.MethodGet, "/ip", nil)
+	req.Host = host
+	req.RemoteAddr = remote
+	for i := 0; i+1 < len(headers); i += 2 {
+		req.Header.Add(headers[i], headers[i+1])
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w.Body.String()
+}
+
+func newIPEngine() *Engine {
+	r := New()
+	r.GET("/ip", func(c *Context) { c.String(http.StatusOK, "%s|%s", c.ClientIP(), c.RemoteIP()) })
+	return r
+}
+
+func TestClientIPUntrusted(t *testing.T) {
+	r := newIPEngine()
+	tests := []struct {
+		remote  string
+		headers []string
+		want    string
+	}{
+		{"10.0.0.1:1234", []string{"X-Forwarded-For", "1.2.3.4"}, "10.0.0.1|10.0.0.1"},
+		{"[::ffff:10.0.0.1]:1234", nil, "10.0.0.1|10.0.0.1"},
+		{"[fe80::1%eth0]:1234", nil, "fe80::1|fe80::1"},
+		{"@", nil, "|"},
+	}
+	for _, tt := range tests {
+		if got := ipRequest(r, "", tt.remote, tt.headers...); got != tt.want {
+			t.Errorf("remote %s = %q, want %q", tt.remote, got, tt.want)
+		}
+	}
+}
+
+func TestClientIPTrustedProxies(t *testing.T) {
+	r := newIPEngine()
+	if err := r.SetTrustedProxies([]string{"10.0.0.0/8", "::1", "::ffff:192.168.0.0/112"}); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		remote  string
+		headers []string
+		want    string
+	}{
+		{"10.0.0.1:1", []string{"X-Forwarded-For", "9.9.9.9, 1.2.3.4", "X-Forwarded-For", "10.1.1.1"}, "1.2.3.4|10.0.0.1"},
+		{"10.0.0.1:1", []string{"X-Forwarded-For", "10.2.2.2, 10.1.1.1"}, "10.2.2.2|10.0.0.1"},
+		{"10.0.0.1:1", []string{"X-Forwarded-For", "junk", "X-Real-IP", "5.5.5.5"}, "5.5.5.5|10.0.0.1"},
+		{"10.0.0.1:1", []string{"X-Forwarded-For", "junk"}, "10.0.0.1|10.0.0.1"},
+		{"192.168.3.4:1", []string{"X-Forwarded-For", "1.2.3.4"}, "1.2.3.4|192.168.3.4"},
+		{"8.8.8.8:1", []string{"X-Forwarded-For", "1.2.3.4"}, "8.8.8.8|8.8.8.8"},
+	}
+	for _, tt := range tests {
+		if got := ipRequest(r, "", tt.remote, tt.headers...); got != tt.want {
+			t.Errorf("remote %s %v = %q, want %q", tt.remote, tt.headers, got, tt.want)
+		}
+	}
+
+	r.RemoteIPHeaders = []string{"Forwarded"}
+	forwarded := `for="[2001:db8::1]:4711";proto=https, for=10.3.3.3`
+	if got := ipRequest(r, "", "[::1]:5", "Forwarded", forwarded); got != "2001:db8::1|::1" {
+		t.Errorf("Forwarded = %q", got)
+	}
+	if got := ipRequest(r, "", "[::1]:5", "Forwarded", "for=unknown"); got != "::1|::1" {
+		t.Errorf("obfuscated Forwarded = %q", got)
+	}
+}
+
+func TestSetTrustedProxiesErrors(t *testing.T) {
+	r := New()
+	if err := r.SetTrustedProxies([]string{"10.0.0.0/8"}); err != nil {
+		t.Fatal(err)
+	}
+	for _, bad := range []string{"bad", "10.0.0.0/33", "::ffff:1.2.3.4/64"} {
+		if err := r.SetTrustedProxies([]string{"127.0.0.1", bad}); err == nil {
+			t.Errorf("SetTrustedProxies(%q) accepted an invalid value", bad)
+		}
+	}
+	want := []string{"10.0.0.0/8"}
+	var got []string
+	for _, p := range r.trustedProxies {
+		got = append(got, p.String())
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("trusted proxies after errors = %v, want %v", got, want)
+	}
+	if err := r.SetTrustedProxies(nil); err != nil || len(r.trustedProxies) != 0 {
+		t.Fatalf("SetTrustedProxies(nil) = %v, %v", err, r.trustedProxies)
+	}
+}
+
+func TestClientIPTrustedPlatform(t *testing.T) {
+	r := newIPEngine()
+	r.TrustedPlatform = PlatformCloudflare
+	if got := ipRequest(r, "", "8.8.8.8:1", "CF-Connecting-IP", "7.7.7.7"); got != "7.7.7.7|8.8.8.8" {
+		t.Errorf("trusted platform = %q", got)
+	}
+	if got := ipRequest(r, "", "8.8.8.8:1", "CF-Connecting-IP", "nope"); got != "8.8.8.8|8.8.8.8" {
+		t.Errorf("invalid platform header = %q", got)
+	}
+
+	api := r.Host("api.example.com")
+	api.GET("/ip", func(c *Context) { c.String(http.StatusOK, c.ClientIP()) })
+	if got := ipRequest(r, "api.example.com", "8.8.8.8:1", "CF-Connecting-IP", "6.6.6.6"); got != "6.6.6.6" {
+		t.Errorf("host engine = %q, want the root engine's platform header to apply", got)
+	}
+}
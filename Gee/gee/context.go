@@ -4,33 +4,80 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
 )
 
 type H map[string]interface{}
 
 // Context 是一个结构体，用于封装HTTP请求处理过程中的上下文信息。
 type Context struct {
-	Writer     http.ResponseWriter // Writer 用于向客户端发送响应
-	Req        *http.Request       // Req 表示客户端发起的HTTP请求
-	engine     *Engine             // engine 是一个Engine类型的指针，用于存储当前应用的Engine实例
-	Path       string              // Path 表示请求的路径
-	Method     string              // Method 表示请求的方法
-	Params     map[string]string   // Params 包含URL中的参数部分，键值对形式
-	StatusCode int                 // StatusCode 用于记录将要发送给客户端的HTTP状态码
-	handler    []Handlerfunc       // handler 是一个Handlerfunc类型的切片，用于存储待处理的处理函数
-	index      int                 // index 表示当前处理函数的索引，用于迭代执行处理函数
+	Writer       ResponseWriter         // Writer 用于向客户端发送响应，同时记录状态码和响应大小
+	Req          *http.Request          // Req 表示客户端发起的HTTP请求
+	engine       *Engine                // engine 是一个Engine类型的指针，用于存储当前应用的Engine实例
+	Path         string                 // Path 表示请求的路径
+	Method       string                 // Method 表示请求的方法
+	Params       map[string]string      // Params 包含URL中的参数部分，键值对形式
+	fullPath     string                 // fullPath 是匹配到的路由模式，例如 /user/:id，没有匹配时为空
+	routeKey     string                 // routeKey 是匹配到的路由的键，格式为 method-pattern，没有匹配时为空
+	StatusCode   int                    // StatusCode 用于记录将要发送给客户端的HTTP状态码
+	handler      []Handlerfunc          // handler 是一个Handlerfunc类型的切片，用于存储待处理的处理函数
+	index        int                    // index 表示当前处理函数的索引，用于迭代执行处理函数
+	Keys         map[string]interface{} // Keys 是请求级别的键值存储，用于在中间件和处理函数之间传递数据
+	mu           sync.RWMutex           // mu 保护Keys的并发读写
+	queryCache   url.Values             // queryCache 缓存解析后的URL查询参数
+	formCache    url.Values             // formCache 缓存解析后的请求体表单
+	rawData      []byte                 // rawData 是GetRawData缓存的请求体
+	rawRead      bool                   // rawRead 表示请求体是否已经被GetRawData读取
+	bodyTooLarge bool                   // bodyTooLarge 表示读取请求体时超出了大小限制
+	clientIP     string                 // clientIP 缓存ClientIP的结果
 }
 
 func newContext(w http.ResponseWriter, req *http.Request) *Context {
+	writer := &responseWriter{}
+	writer.reset(w)
 	return &Context{
-		Writer: w,
+		Writer: writer,
 		Req:    req,
 		Path:   req.URL.Path,
 		Method: req.Method,
+		Params: mountedParams(req),
 		index:  -1,
 	}
 }
 
+// NewContext 创建一个属于engine的Context，不经过路由直接调用处理函数时使用，例如在单元测试中。
+func (engine *Engine) NewContext(w http.ResponseWriter, req *http.Request) *Context {
+	c := newContext(w, req)
+	c.engine = engine
+	return c
+}
+
+// RunHandlers 以给定的处理函数链从头处理当前请求，中间件中的Next和Abort照常生效。
+func (c *Context) RunHandlers(handlers ...Handlerfunc) {
+	c.handler = handlers
+	c.index = -1
+	c.Next()
+}
+
+// setParams 合并路由匹配到的参数，挂载的子引擎会保留父路由中的参数。
+func (c *Context) setParams(params map[string]string) {
+	if c.Params == nil {
+		c.Params = params
+		return
+	}
+	for k, v := range params {
+		c.Params[k] = v
+	}
+}
+
+// FullPath 返回匹配到的路由模式（例如 /user/:id），没有匹配到路由时返回空字符串。
+// 与Path不同，它不包含具体的参数值，适合用作日志或监控指标的标签。
+func (c *Context) FullPath() string {
+	return c.fullPath
+}
+
 // Next 方法用于执行下一个处理程序。
 // 在Context中，它通过递增索引来遍历并执行所有的处理程序。
 func (c *Context) Next() {
@@ -41,6 +88,57 @@ func (c *Context) Next() {
 	}
 }
 
+// Abort 终止处理链，后续的处理函数不会再被执行。
+// 已经在执行中的处理函数（调用了Next的中间件）仍会正常返回。
+func (c *Context) Abort() {
+	c.index = len(c.handler)
+}
+
+// IsAborted 返回处理链是否已经被终止。
+func (c *Context) IsAborted() bool {
+	return c.index >= len(c.handler)
+}
+
+// AbortWithStatus 写入状态码并终止处理链。
+func (c *Context) AbortWithStatus(code int) {
+	c.Status(code)
+	c.Abort()
+}
+
+// Set 在当前请求的上下文中保存一个键值对。
+func (c *Context) Set(key string, value interface{}) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.Keys == nil {
+		c.Keys = make(map[string]interface{})
+	}
+	c.Keys[key] = value
+}
+
+// Get 读取通过Set保存的值，exists表示该键是否存在。
+func (c *Context) Get(key string) (value interface{}, exists bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	value, exists = c.Keys[key]
+	return
+}
+
+// MustGet 读取通过Set保存的值，键不存在时panic。
+func (c *Context) MustGet(key string) interface{} {
+	if value, exists := c.Get(key); exists {
+		return value
+	}
+	panic("Key \"" + key + "\" does not exist")
+}
+
+// GetString 读取通过Set保存的字符串值，键不存在或类型不符时返回空字符串。
+func (c *Context) GetString(key string) (s string) {
+	if val, ok := c.Get(key); ok && val != nil {
+		s, _ = val.(string)
+	}
+	return
+}
+
 // Param 通过键获取路径参数的值。
 // 参数：
 //
@@ -57,7 +155,30 @@ func (c *Context) Param(key string) string {
 	return "" // 如果键不存在，则返回空字符串
 }
 
-// PostForm 获取POST表单中指定键的值。
+// ParamInt 将路径参数解析为int，参数不存在或格式错误时返回错误。
+// 通常与 :id<int> 约束一起使用，此时解析不会失败。
+func (c *Context) ParamInt(key string) (int, error) {
+	return strconv.Atoi(c.Param(key))
+}
+
+// ParamInt64 将路径参数解析为int64。
+func (c *Context) ParamInt64(key string) (int64, error) {
+	return strconv.ParseInt(c.Param(key), 10, 64)
+}
+
+// ParamUint64 将路径参数解析为uint64。
+func (c *Context) ParamUint64(key string) (uint64, error) {
+	return strconv.ParseUint(c.Param(key), 10, 64)
+}
+
+// ParamFloat64 将路径参数解析为float64。
+func (c *Context) ParamFloat64(key string) (float64, error) {
+	return strconv.ParseFloat(c.Param(key), 64)
+}
+
+// PostForm 获取请求体表单（application/x-www-form-urlencoded或multipart/form-data）中指定键的第一个值，
+// 请求体表单中没有该键时回退到URL查询参数，与http.Request.FormValue相同。
+// 只读取请求体表单时使用GetPostForm、DefaultPostForm等方法。
 // 参数：
 //
 //	key: 要获取表单值的键。
@@ -66,7 +187,11 @@ func (c *Context) Param(key string) string {
 //
 //	string: 如果找到键，则返回对应的表单值；否则返回空字符串。
 func (c *Context) PostForm(key string) string {
-	return c.Req.FormValue(key) // 直接从请求的表单中获取指定键的值
+	if value, ok := c.GetPostForm(key); ok { // 从缓存的请求体表单中获取指定键的第一个值
+		return value
+	}
+	value, _ := c.GetQuery(key)
+	return value
 }
 
 //Query 从请求的URL查询参数中获取指定键(key)对应的值。
@@ -103,8 +228,8 @@ func (c *Context) PostForm(key string) string {
 // 用于存储 URL 查询参数。键（key）是查询参数名，
 // 值（value）是一个字符串切片，因为一个参数名可能对应多个值。
 func (c *Context) Query(key string) string {
-
-	return c.Req.URL.Query().Get(key)
+	value, _ := c.GetQuery(key) // 查询参数只解析一次，缓存在Context中
+	return value
 }
 
 // Status 设置响应的状态码，并通过响应写入器写入该状态码。
@@ -140,7 +265,7 @@ func (c *Context) SetHeader(key string, value string) {
 // err: 错误信息，将以JSON格式返回给客户端。
 func (c *Context) Fail(code int, err string) {
 	// 设置当前处理进度索引为handler数组的长度，以表示处理结束
-	c.index = len(c.handler)
+	c.Abort()
 	// 使用指定的状态码和错误信息生成JSON响应
 	c.JSON(code, H{"msg": err})
 }
@@ -201,10 +326,15 @@ func (c *Context) Data(code int, data []byte) {
 	c.Writer.Write(data)
 }
 
+// HTML 渲染名为name的模板并以text/html返回。
+// 模板先渲染到缓冲区，渲染失败时返回500而不会输出不完整的页面。
 func (c *Context) HTML(code int, name string, data interface{}) {
-	c.SetHeader("Content-Type", "text/html")
-	c.Status(code)
-	if err := c.engine.htmlTemplates.ExecuteTemplate(c.Writer, name, data); err != nil {
-		c.Fail(500, err.Error())
+	body, err := c.engine.renderHTML(name, data)
+	if err != nil {
+		c.Fail(http.StatusInternalServerError, err.Error())
+		return
 	}
+	c.SetHeader("Content-Type", "text/html; charset=utf-8")
+	c.Status(code)
+	c.Writer.Write(body)
 }
@@ -0,0 +1,49 @@
+package gee
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestContextKeys(t *testing.T) {
+	c := newContext(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	if _, ok := c.Get("user"); ok {
+		t.Fatal("empty context has a key")
+	}
+	c.Set("user", "u1")
+	c.Set("n", 1)
+	if v, ok := c.Get("user"); !ok || v != "u1" {
+		t.Fatalf("Get = %v, %v", v, ok)
+	}
+	if c.GetString("user") != "u1" || c.GetString("n") != "" || c.GetString("missing") != "" {
+		t.Fatal("GetString returned an unexpected value")
+	}
+	if c.MustGet("n") != 1 {
+		t.Fatal("MustGet returned an unexpected value")
+	}
+	defer func() {
+		if recover() == nil {
+			t.Fatal("MustGet of a missing key did not panic")
+		}
+	}()
+	c.MustGet("missing")
+}
+
+func TestContextAbort(t *testing.T) {
+	var order []string
+	r := New()
+	r.Use(func(c *Context) {
+		order = append(order, "a")
+		c.AbortWithStatus(http.StatusForbidden)
+		if !c.IsAborted() {
+			t.Error("IsAborted = false after Abort")
+		}
+	})
+	r.GET("/", func(c *Context) { order = append(order, "handler") })
+
+	w := performRequest(r, http.MethodGet, "/", nil)
+	if w.Code != http.StatusForbidden || len(order) != 1 {
+		t.Fatalf("status = %d, order = %v", w.Code, order)
+	}
+}
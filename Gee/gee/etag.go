@@ -0,0 +1,262 @@
+package gee
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// ComputeETag 根据内容计算ETag，返回带引号的形式，weak为true时加上W/前缀。
+func ComputeETag(data []byte, weak bool) string {
+	sum := sha256.Sum256(data)
+	tag := `"` + hex.EncodeToString(sum[:16]) + `"`
+	if weak {
+		return "W/" + tag
+	}
+	return tag
+}
+
+// CheckPreconditions 按RFC 9110的顺序检查If-Match、If-Unmodified-Since、If-None-Match和If-Modified-Since。
+// etag为资源当前的ETag（带引号，可以为空），modified为资源的最后修改时间（可以为零值）。
+// 条件不满足时写出304（GET、HEAD）或412（其他方法）并终止处理链，返回true，处理函数应直接返回；
+// 否则设置ETag和Last-Modified响应头并返回false。
+// 在渲染或修改资源之前调用，可以跳过不必要的工作：
+//
+//	if c.CheckPreconditions(article.ETag(), article.Updated) {
+//		return
+//	}
+func (c *Context) CheckPreconditions(etag string, modified time.Time) bool {
+	h := c.Writer.Header()
+	if etag != "" {
+		h.Set("ETag", etag)
+	}
+	if !modified.IsZero() {
+		h.Set("Last-Modified", modified.UTC().Format(http.TimeFormat))
+	}
+	code := evalPreconditions(c.Req, etag, modified)
+	if code == 0 {
+		return false
+	}
+	writeNotModified(h, code)
+	c.Writer.WriteHeader(code)
+	c.Abort()
+	return true
+}
+
+// evalPreconditions 计算条件请求的结果，返回304、412，条件满足时返回0。
+func evalPreconditions(req *http.Request, etag string, modified time.Time) int {
+	safe := req.Method == http.MethodGet || req.Method == http.MethodHead
+	if im := req.Header.Get("If-Match"); im != "" {
+		if !matchETag(im, etag, false) {
+			return http.StatusPreconditionFailed
+		}
+	} else if ius := req.Header.Get("If-Unmodified-Since"); ius != "" && !modified.IsZero() {
+		if t, err := http.ParseTime(ius); err == nil && modified.Truncate(time.Second).After(t) {
+			return http.StatusPreconditionFailed
+		}
+	}
+	if inm := req.Header.Get("If-None-Match"); inm != "" {
+		if matchETag(inm, etag, true) {
+			if safe {
+				return http.StatusNotModified
+			}
+			return http.StatusPreconditionFailed
+		}
+	} else if ims := req.Header.Get("If-Modified-Since"); ims != "" && safe && !modified.IsZero() {
+		if t, err := http.ParseTime(ims); err == nil && !modified.Truncate(time.Second).After(t) {
+			return http.StatusNotModified
+		}
+	}
+	return 0
+}
+
+// matchETag 判断etag是否出现在If-Match或If-None-Match的列表中。
+// weak为true时使用弱比较（忽略W/前缀），否则使用强比较（弱ETag不匹配任何值）。
+// "*" 匹配任意存在的资源。
+func matchETag(list, etag string, weak bool) bool {
+	if strings.TrimSpace(list) == "*" {
+		return etag != ""
+	}
+	if etag == "" || (!weak && strings.HasPrefix(etag, "W/")) {
+		return false
+	}
+	for _, candidate := range strings.Split(list, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "" {
+			continue
+		}
+		if weak {
+			if strings.TrimPrefix(candidate, "W/") == strings.TrimPrefix(etag, "W/") {
+				return true
+			}
+		} else if candidate == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// writeNotModified 去掉304和412响应中不应出现的实体头。
+func writeNotModified(h http.Header, code int) {
+	h.Del("Content-Type")
+	h.Del("Content-Length")
+	h.Del("Content-Encoding")
+	if code == http.StatusPreconditionFailed {
+		h.Del("ETag")
+		h.Del("Last-Modified")
+	}
+}
+
+// ETagConfig 用于配置ETag中间件。
+type ETagConfig struct {
+	// Weak 为true时生成弱ETag，适合内容等价但字节可能不同的响应，例如压缩后的内容。
+	Weak bool
+	// MaxBytes 缓冲的最大响应体字节数，超过后直接写出响应且不生成ETag，小于等于0时使用1MB。
+	MaxBytes int
+}
+
+// ETag 返回生成强ETag的中间件，参见ETagWithConfig。
+func ETag() Handlerfunc {
+	return ETagWithConfig(ETagConfig{})
+}
+
+// ETagWithConfig 返回ETag中间件：缓冲GET和HEAD请求的200响应，按内容计算ETag，
+// 并根据If-None-Match、If-Modified-Since（对比响应的Last-Modified）返回304，
+// 根据If-Match、If-Unmodified-Since返回412。处理函数已经设置ETag时使用它的值。
+// 流式响应（调用了Flush）和过大的响应不会被缓冲。
+// 修改资源的请求需要在处理函数中调用Context.CheckPreconditions，中间件无法在执行之前得知资源的ETag。
+func ETagWithConfig(conf ETagConfig) Handlerfunc {
+	if conf.MaxBytes <= 0 {
+		conf.MaxBytes = 1 << 20
+	}
+	return func(c *Context) {
+		if c.Method != http.MethodGet && c.Method != http.MethodHead {
+			c.Next()
+			return
+		}
+		w := &etagWriter{ResponseWriter: c.Writer, status: http.StatusOK, limit: conf.MaxBytes}
+		c.Writer = w
+		defer func() { c.Writer = w.ResponseWriter }()
+
+		c.Next()
+
+		if w.passthrough {
+			return
+		}
+		h := w.Header()
+		if w.wroteHeader && w.status == http.StatusOK {
+			etag := h.Get("ETag")
+			if etag == "" {
+				etag = ComputeETag(w.buf.Bytes(), conf.Weak)
+				h.Set("ETag", etag)
+			}
+			var modified time.Time
+			if lm := h.Get("Last-Modified"); lm != "" {
+				modified, _ = http.ParseTime(lm)
+			}
+			if code := evalPreconditions(c.Req, etag, modified); code != 0 {
+				writeNotModified(h, code)
+				w.ResponseWriter.WriteHeader(code)
+				return
+			}
+		}
+		w.flushBuffer()
+	}
+}
+
+// etagWriter 缓冲响应头和响应体，直到ETag中间件计算完ETag后再写出。
+type etagWriter struct {
+	ResponseWriter
+	buf         bytes.Buffer
+	status      int
+	wroteHeader bool
+	limit       int
+	passthrough bool // 已经切换为直接写出
+}
+
+func (w *etagWriter) WriteHeader(code int) {
+	if w.passthrough {
+		w.ResponseWriter.WriteHeader(code)
+		return
+	}
+	if code > 0 && !w.wroteHeader {
+		w.status = code
+		w.wroteHeader = true
+	}
+}
+
+func (w *etagWriter) WriteHeaderNow() {
+	if w.passthrough {
+		w.ResponseWriter.WriteHeaderNow()
+		return
+	}
+	w.wroteHeader = true
+}
+
+func (w *etagWriter) Write(data []byte) (int, error) {
+	if w.passthrough {
+		return w.ResponseWriter.Write(data)
+	}
+	w.wroteHeader = true
+	if w.buf.Len()+len(data) > w.limit {
+		w.flushBuffer()
+		return w.ResponseWriter.Write(data)
+	}
+	return w.buf.Write(data)
+}
+
+func (w *etagWriter) Status() int {
+	if w.passthrough {
+		return w.ResponseWriter.Status()
+	}
+	return w.status
+}
+
+func (w *etagWriter) Size() int {
+	if w.passthrough {
+		return w.ResponseWriter.Size()
+	}
+	if !w.wroteHeader {
+		return noWritten
+	}
+	return w.buf.Len()
+}
+
+func (w *etagWriter) Written() bool {
+	if w.passthrough {
+		return w.ResponseWriter.Written()
+	}
+	return w.wroteHeader
+}
+
+// Flush 表示这是流式响应，写出已缓冲的内容并切换为直接写出。
+func (w *etagWriter) Flush() {
+	w.flushBuffer()
+	w.ResponseWriter.Flush()
+}
+
+func (w *etagWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	w.passthrough = true
+	return w.ResponseWriter.Hijack()
+}
+
+// flushBuffer 写出缓冲的状态码和响应体，之后的写入直接交给底层的ResponseWriter。
+func (w *etagWriter) flushBuffer() {
+	if w.passthrough {
+		return
+	}
+	w.passthrough = true
+	if !w.wroteHeader {
+		return
+	}
+	w.ResponseWriter.WriteHeader(w.status)
+	if w.buf.Len() > 0 {
+		w.ResponseWriter.Write(w.buf.Bytes())
+	}
+	w.buf.Reset()
+}
@@ -0,0 +1,144 @@
+package gee
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestComputeETag(t *testing.T) {
+	strong := ComputeETag([]byte("hello"), false)
+	if len(strong) != 34 || strong[0] != '"' || strong[33] != '"' {
+		t.Fatalf("ComputeETag = %s", strong)
+	}
+	if weak := ComputeETag([]byte("hello"), true); weak != "W/"+strong {
+		t.Fatalf("weak ETag = %s, want W/%s", weak, strong)
+	}
+	if ComputeETag([]byte("hello!"), false) == strong {
+		t.Fatal("different content produced the same ETag")
+	}
+}
+
+func TestMatchETag(t *testing.T) {
+	tests := []struct {
+		list, etag string
+		weak, want bool
+	}{
+		{`"a"`, `"a"`, false, true},
+		{`"b", "a"`, `"a"`, false, true},
+		{`W/"a"`, `"a"`, false, false},
+		{`"a"`, `W/"a"`, false, false},
+		{`W/"a"`, `"a"`, true, true},
+		{`"a"`, `W/"a"`, true, true},
+		{`*`, `"a"`, false, true},
+		{`*`, ``, true, false},
+		{`"a"`, `"b"`, true, false},
+	}
+	for _, tt := range tests {
+		if got := matchETag(tt.list, tt.etag, tt.weak); got != tt.want {
+			t.Errorf("matchETag(%s, %s, %v) = %v, want %v", tt.list, tt.etag, tt.weak, got, tt.want)
+		}
+	}
+}
+
+func TestETagMiddleware(t *testing.T) {
+	modified := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	r := New()
+	r.Use(ETagWithConfig(ETagConfig{MaxBytes: 16}))
+	jsonHandler := func(c *Context) { c.JSON(http.StatusOK, H{"a": 1}) }
+	r.GET("/json", jsonHandler)
+	r.Handle(http.MethodHead, "/json", jsonHandler)
+	r.GET("/tagged", func(c *Context) {
+		c.SetHeader("ETag", `"custom"`)
+		c.SetHeader("Last-Modified", modified.Format(http.TimeFormat))
+		c.String(http.StatusOK, "tagged")
+	})
+	r.GET("/large", func(c *Context) { c.String(http.StatusOK, strings.Repeat("x", 32)) })
+	r.GET("/stream", func(c *Context) {
+		c.String(http.StatusOK, "part")
+		c.Writer.Flush()
+	})
+	r.GET("/missing", func(c *Context) { c.String(http.StatusNotFound, "missing") })
+
+	w := performRequest(r, http.MethodGet, "/json", nil)
+	etag := w.Header().Get("ETag")
+	if w.Code != http.StatusOK || etag != ComputeETag(w.Body.Bytes(), false) {
+		t.Fatalf("GET /json = %d ETag %s body %q", w.Code, etag, w.Body)
+	}
+	w = performRequest(r, http.MethodGet, "/json", nil, "If-None-Match", "W/"+etag)
+	if w.Code != http.StatusNotModified || w.Body.Len() != 0 || w.Header().Get("Content-Type") != "" || w.Header().Get("ETag") != etag {
+		t.Fatalf("If-None-Match = %d %v %q", w.Code, w.Header(), w.Body)
+	}
+	if w := performRequest(r, http.MethodGet, "/json", nil, "If-Match", `"other"`); w.Code != http.StatusPreconditionFailed || w.Header().Get("ETag") != "" {
+		t.Fatalf("If-Match = %d %v", w.Code, w.Header())
+	}
+	if w := performRequest(r, http.MethodHead, "/json", nil, "If-None-Match", etag); w.Code != http.StatusNotModified {
+		t.Fatalf("HEAD If-None-Match = %d", w.Code)
+	}
+
+	if w := performRequest(r, http.MethodGet, "/tagged", nil, "If-None-Match", `"custom"`); w.Code != http.StatusNotModified {
+		t.Fatalf("handler ETag = %d %v", w.Code, w.Header())
+	}
+	if w := performRequest(r, http.MethodGet, "/tagged", nil, "If-Modified-Since", modified.Format(http.TimeFormat)); w.Code != http.StatusNotModified {
+		t.Fatalf("If-Modified-Since = %d", w.Code)
+	}
+	earlier := modified.Add(-time.Hour).Format(http.TimeFormat)
+	if w := performRequest(r, http.MethodGet, "/tagged", nil, "If-Modified-Since", earlier); w.Code != http.StatusOK || w.Body.String() != "tagged" {
+		t.Fatalf("stale If-Modified-Since = %d %q", w.Code, w.Body)
+	}
+
+	for _, path := range []string{"/large", "/stream", "/missing"} {
+		w := performRequest(r, http.MethodGet, path, nil)
+		if w.Header().Get("ETag") != "" || w.Body.Len() == 0 {
+			t.Errorf("GET %s = %d ETag %q body %q, want the response passed through", path, w.Code, w.Header().Get("ETag"), w.Body)
+		}
+	}
+	if w := performRequest(r, http.MethodGet, "/large", nil); w.Body.Len() != 32 {
+		t.Errorf("large body = %d bytes, want 32", w.Body.Len())
+	}
+}
+
+func TestCheckPreconditions(t *testing.T) {
+	modified := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	rendered := 0
+	handler := func(c *Context) {
+		if c.CheckPreconditions(`"v1"`, modified) {
+			return
+		}
+		rendered++
+		c.String(http.StatusOK, "ok")
+	}
+	r := New()
+	r.GET("/doc", handler)
+	r.POST("/doc", handler)
+
+	tests := []struct {
+		method  string
+		headers []string
+		code    int
+	}{
+		{http.MethodGet, nil, http.StatusOK},
+		{http.MethodGet, []string{"If-None-Match", `"v1"`}, http.StatusNotModified},
+		{http.MethodGet, []string{"If-Modified-Since", modified.Format(http.TimeFormat)}, http.StatusNotModified},
+		{http.MethodGet, []string{"If-None-Match", `"v0"`, "If-Modified-Since", modified.Format(http.TimeFormat)}, http.StatusOK},
+		{http.MethodPost, []string{"If-Match", `"v0"`}, http.StatusPreconditionFailed},
+		{http.MethodPost, []string{"If-Match", `"v1"`}, http.StatusOK},
+		{http.MethodPost, []string{"If-Match", "*"}, http.StatusOK},
+		{http.MethodPost, []string{"If-None-Match", "*"}, http.StatusPreconditionFailed},
+		{http.MethodPost, []string{"If-Unmodified-Since", modified.Add(-time.Hour).Format(http.TimeFormat)}, http.StatusPreconditionFailed},
+		{http.MethodPost, []string{"If-Unmodified-Since", modified.Format(http.TimeFormat)}, http.StatusOK},
+	}
+	for _, tt := range tests {
+		w := performRequest(r, tt.method, "/doc", nil, tt.headers...)
+		if w.Code != tt.code {
+			t.Errorf("%s %v = %d, want %d", tt.method, tt.headers, w.Code, tt.code)
+		}
+		if w.Code == http.StatusOK && (w.Header().Get("ETag") != `"v1"` || w.Header().Get("Last-Modified") != modified.Format(http.TimeFormat)) {
+			t.Errorf("%s %v headers = %v", tt.method, tt.headers, w.Header())
+		}
+	}
+	if rendered != 5 {
+		t.Fatalf("rendered %d times, want 5", rendered)
+	}
+}
@@ -0,0 +1,97 @@
+package gee
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// NoRoute 设置分组内没有匹配路由时执行的处理函数。
+// 在Engine上调用时作用于所有请求；在子分组上调用时只作用于该分组前缀下的请求，
+// 例如让 /api 返回JSON格式的404，而站点其他部分返回HTML页面。
+// 处理函数在匹配到的分组中间件之后执行，因此日志、CORS和Recovery等中间件仍然生效。
+// 处理函数没有写出响应时，会返回默认的404响应。
+func (group *RouteGroup) NoRoute(handlers ...Handlerfunc) {
+	group.noRoute = handlers
+}
+
+// NoMethod 设置分组内路径存在、但请求方法不匹配时执行的处理函数，并开启HandleMethodNotAllowed。
+// 执行前会设置Allow响应头；处理函数没有写出响应时，会返回默认的405响应。
+func (group *RouteGroup) NoMethod(handlers ...Handlerfunc) {
+	group.noMethod = handlers
+	group.engine.HandleMethodNotAllowed = true
+}
+
+// inGroup 判断路径是否位于分组前缀之下。按路径段匹配，"/api"不会匹配"/apix"，
+// 前缀中的 ":param" 匹配任意一段，"*" 匹配剩余的全部路径。
+func (group *RouteGroup) inGroup(path string) bool {
+	prefixParts := parsePattern(group.prefix)
+	pathParts := parsePattern(path)
+	for i, part := range prefixParts {
+		if part[0] == '*' {
+			return true
+		}
+		if i >= len(pathParts) {
+			return false
+		}
+		if part[0] != ':' && part != pathParts[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// fallbackGroup 在包含该路径的分组中找出前缀最长、且设置了对应处理函数的分组。
+func (engine *Engine) fallbackGroup(path string, pick func(*RouteGroup) []Handlerfunc) []Handlerfunc {
+	var best *RouteGroup
+	for _, group := range engine.groups {
+		if len(pick(group)) == 0 || !group.inGroup(path) {
+			continue
+		}
+		if best == nil || len(group.prefix) > len(best.prefix) {
+			best = group
+		}
+	}
+	if best == nil {
+		return nil
+	}
+	return pick(best)
+}
+
+// allowedMethods 返回能够匹配该路径的其他请求方法。
+func (r *router) allowedMethods(path, method string) []string {
+	var allowed []string
+	for m := range r.roots {
+		if m == method {
+			continue
+		}
+		if n, _ := r.getRoute(m, path); n != nil {
+			allowed = append(allowed, m)
+		}
+	}
+	sort.Strings(allowed)
+	return allowed
+}
+
+// fallback 返回没有匹配到路由时需要执行的处理函数链。
+func (r *router) fallback(c *Context) []Handlerfunc {
+	engine := c.engine
+	if engine.methodNotAllowed() {
+		if allowed := r.allowedMethods(c.Path, c.Method); len(allowed) > 0 {
+			c.SetHeader("Allow", strings.Join(allowed, ", "))
+			handlers := engine.fallbackGroup(c.Path, func(g *RouteGroup) []Handlerfunc { return g.noMethod })
+			return append(handlers[:len(handlers):len(handlers)], defaultFallback(http.StatusMethodNotAllowed, "405 METHOD NOT ALLOWED: %s\n"))
+		}
+	}
+	handlers := engine.fallbackGroup(c.Path, func(g *RouteGroup) []Handlerfunc { return g.noRoute })
+	return append(handlers[:len(handlers):len(handlers)], defaultFallback(http.StatusNotFound, "404 NOT FOUND: %s\n"))
+}
+
+// defaultFallback 在前面的处理函数都没有写出响应时返回默认的错误页面。
+func defaultFallback(code int, format string) Handlerfunc {
+	return func(c *Context) {
+		if !c.Writer.Written() {
+			c.String(code, format, c.Path)
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package gee
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestNoRoute(t *testing.T) {
+	r := New()
+	r.Use(func(c *Context) { c.SetHeader("X-Middleware", "1"); c.Next() })
+	r.GET("/", func(c *Context) { c.String(http.StatusOK, "home") })
+	api := r.Group("/api")
+	api.GET("/users", func(c *Context) { c.String(http.StatusOK, "users") })
+	api.NoRoute(func(c *Context) { c.JSON(http.StatusNotFound, H{"error": "not found"}) })
+	r.NoRoute(func(c *Context) { c.String(http.StatusNotFound, "<h1>missing</h1>") })
+
+	tests := []struct {
+		path string
+		code int
+		body string
+	}{
+		{"/api/nope", http.StatusNotFound, `{"error":"not found"}`},
+		{"/apix", http.StatusNotFound, "<h1>missing</h1>"},
+		{"/nope", http.StatusNotFound, "<h1>missing</h1>"},
+		{"/api/users", http.StatusOK, "users"},
+	}
+	for _, tt := range tests {
+		w := performRequest(r, http.MethodGet, tt.path, nil)
+		if w.Code != tt.code || strings.TrimSpace(w.Body.String()) != tt.body {
+			t.Errorf("GET %s = %d %q, want %d %q", tt.path, w.Code, w.Body.String(), tt.code, tt.body)
+		}
+		if w.Header().Get("X-Middleware") != "1" {
+			t.Errorf("GET %s: group middleware did not run", tt.path)
+		}
+	}
+}
+
+func TestDefaultNotFound(t *testing.T) {
+	r := New()
+	r.NoRoute(func(c *Context) { c.SetHeader("X-Handled", "1") })
+
+	w := performRequest(r, http.MethodGet, "/nope", nil)
+	if w.Code != http.StatusNotFound || w.Body.String() != "404 NOT FOUND: /nope\n" || w.Header().Get("X-Handled") != "1" {
+		t.Fatalf("got %d %q %v", w.Code, w.Body.String(), w.Header())
+	}
+}
+
+func TestNoMethod(t *testing.T) {
+	r := New()
+	r.GET("/users", func(c *Context) {})
+	r.Handle(http.MethodDelete, "/users", func(c *Context) {})
+
+	// 默认不区分405
+	if w := performRequest(r, http.MethodPost, "/users", nil); w.Code != http.StatusNotFound {
+		t.Fatalf("status = %d, want 404", w.Code)
+	}
+
+	r.HandleMethodNotAllowed = true
+	w := performRequest(r, http.MethodPost, "/users", nil)
+	if w.Code != http.StatusMethodNotAllowed || w.Header().Get("Allow") != "DELETE, GET" {
+		t.Fatalf("got %d Allow=%q", w.Code, w.Header().Get("Allow"))
+	}
+
+	r.NoMethod(func(c *Context) { c.String(http.StatusMethodNotAllowed, "use %s", c.Writer.Header().Get("Allow")) })
+	if w := performRequest(r, http.MethodPut, "/users", nil); w.Body.String() != "use DELETE, GET" {
+		t.Fatalf("body = %q", w.Body.String())
+	}
+	if w := performRequest(r, http.MethodPost, "/other", nil); w.Code != http.StatusNotFound {
+		t.Fatalf("unknown path status = %d, want 404", w.Code)
+	}
+}
+
+func TestInGroup(t *testing.T) {
+	tests := []struct {
+		prefix, path string
+		want         bool
+	}{
+		{"", "/anything", true},
+		{"/api", "/api", true},
+		{"/api", "/api/v1/users", true},
+		{"/api", "/apix", false},
+		{"/t/:tenant", "/t/acme/x", true},
+		{"/t/:tenant", "/t", false},
+	}
+	for _, tt := range tests {
+		g := &RouteGroup{prefix: tt.prefix}
+		if got := g.inGroup(tt.path); got != tt.want {
+			t.Errorf("inGroup(%q, %q) = %v, want %v", tt.prefix, tt.path, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package gee
+
+import (
+	"fmt"
+	"net/http"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// File 把本地文件写入响应。文件不存在或是目录时返回404。
+// 响应带有ETag和Last-Modified，支持Range断点续传、If-Range以及If-None-Match等条件请求。
+// file应由服务端决定，不要直接使用客户端传入的路径。
+func (c *Context) File(file string) {
+	c.FileFromFS(filepath.Base(file), Dir(filepath.Dir(file), false))
+}
+
+// FileFromFS 把文件系统fs中的文件name写入响应，行为与File相同。
+// name会先被清理，无法通过 ".." 访问fs之外的文件。
+func (c *Context) FileFromFS(name string, fs http.FileSystem) {
+	name = path.Clean("/" + name)
+	f, stat, err := openStatic(fs, name)
+	if err != nil || stat.IsDir() {
+		if f != nil {
+			f.Close()
+		}
+		c.Status(http.StatusNotFound)
+		return
+	}
+	defer f.Close()
+	serveStaticFile(c, fs, f, stat, name, StaticConfig{ETag: true})
+}
+
+// FileAttachment 以附件形式返回本地文件，浏览器会以filename为文件名下载，
+// 而不是直接显示。filename可以包含非ASCII字符，按RFC 6266同时提供filename和filename*。
+func (c *Context) FileAttachment(file, filename string) {
+	c.SetHeader("Content-Disposition", contentDisposition("attachment", filename))
+	c.File(file)
+}
+
+// contentDisposition 生成Content-Disposition头。非ASCII或特殊字符的文件名在filename中被替换为"_"，
+// 完整的文件名以RFC 5987编码放在filename*中，供支持的客户端使用。
+func contentDisposition(dispositionType, filename string) string {
+	var fallback strings.Builder
+	plain := true
+	for _, r := range filename {
+		if r < 0x20 || r >= 0x7f || r == '"' || r == '\\' {
+			fallback.WriteByte('_')
+			plain = false
+			continue
+		}
+		fallback.WriteRune(r)
+	}
+	value := fmt.Sprintf(`%s; filename="%s"`, dispositionType, fallback.String())
+	if !plain {
+		value += "; filename*=UTF-8''" + encodeRFC5987(filename)
+	}
+	return value
+}
+
+// encodeRFC5987 对RFC 5987 attr-char以外的字节进行百分号编码。
+func encodeRFC5987(s string) string {
+	const hexDigits = "0123456789ABCDEF"
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		ch := s[i]
+		if ('a' <= ch && ch <= 'z') || ('A' <= ch && ch <= 'Z') || ('0' <= ch && ch <= '9') ||
+			strings.IndexByte("!#$&+-.^_`|~", ch) >= 0 {
+			b.WriteByte(ch)
+			continue
+		}
+		b.WriteByte('%')
+		b.WriteByte(hexDigits[ch>>4])
+		b.WriteByte(hexDigits[ch&0xf])
+	}
+	return b.String()
+}
+
+// Redirect 把请求重定向到location。code只能是201或300、301、302、303、307、308，
+// 其他状态码属于程序错误，会导致panic。
+func (c *Context) Redirect(code int, location string) {
+	if (code < http.StatusMultipleChoices || code > http.StatusPermanentRedirect ||
+		code == http.StatusNotModified || code == http.StatusUseProxy || code == 306) && code != http.StatusCreated {
+		panic(fmt.Sprintf("gee: cannot redirect with status code %d", code))
+	}
+	c.StatusCode = code
+	http.Redirect(c.Writer, c.Req, location, code)
+}
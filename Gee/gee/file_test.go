@@ -0,0 +1,84 @@
+package gee
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+)
+
+func TestFile(t *testing.T) {
+	dir := writeFiles(t, map[string]string{"data.txt": "0123456789", "sub/inner.txt": "inner"})
+	file := filepath.Join(dir, "data.txt")
+	r := New()
+	r.GET("/file", func(c *Context) { c.File(file) })
+	r.GET("/dir", func(c *Context) { c.File(filepath.Join(dir, "sub")) })
+	r.GET("/missing", func(c *Context) { c.File(filepath.Join(dir, "missing.txt")) })
+	r.GET("/fs/*name", func(c *Context) { c.FileFromFS(c.Param("name"), http.Dir(filepath.Join(dir, "sub"))) })
+
+	w := performRequest(r, http.MethodGet, "/file", nil)
+	etag := w.Header().Get("ETag")
+	if w.Code != http.StatusOK || w.Body.String() != "0123456789" || etag == "" || w.Header().Get("Last-Modified") == "" {
+		t.Fatalf("GET /file = %d %v %q", w.Code, w.Header(), w.Body)
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "text/plain; charset=utf-8" {
+		t.Errorf("Content-Type = %q", ct)
+	}
+	if w := performRequest(r, http.MethodGet, "/file", nil, "If-None-Match", etag); w.Code != http.StatusNotModified {
+		t.Errorf("If-None-Match = %d", w.Code)
+	}
+	if w := performRequest(r, http.MethodGet, "/file", nil, "Range", "bytes=2-4", "If-Range", etag); w.Code != http.StatusPartialContent || w.Body.String() != "234" {
+		t.Errorf("Range with matching If-Range = %d %q", w.Code, w.Body)
+	}
+	if w := performRequest(r, http.MethodGet, "/file", nil, "Range", "bytes=2-4", "If-Range", `"old"`); w.Code != http.StatusOK || w.Body.Len() != 10 {
+		t.Errorf("Range with stale If-Range = %d %q", w.Code, w.Body)
+	}
+
+	for _, path := range []string{"/dir", "/missing", "/fs/../data.txt", "/fs/nope.txt"} {
+		if w := performRequest(r, http.MethodGet, path, nil); w.Code != http.StatusNotFound {
+			t.Errorf("GET %s = %d %q, want 404", path, w.Code, w.Body)
+		}
+	}
+	if w := performRequest(r, http.MethodGet, "/fs/inner.txt", nil); w.Body.String() != "inner" {
+		t.Errorf("FileFromFS = %d %q", w.Code, w.Body)
+	}
+}
+
+func TestFileAttachment(t *testing.T) {
+	file := filepath.Join(writeFiles(t, map[string]string{"report.csv": "a,b"}), "report.csv")
+	tests := []struct {
+		filename, want string
+	}{
+		{"report.csv", `attachment; filename="report.csv"`},
+		{`报告 "v1".txt`, `attachment; filename="__ _v1_.txt"; filename*=UTF-8''%E6%8A%A5%E5%91%8A%20%22v1%22.txt`},
+	}
+	for _, tt := range tests {
+		r := New()
+		r.GET("/download", func(c *Context) { c.FileAttachment(file, tt.filename) })
+		w := performRequest(r, http.MethodGet, "/download", nil)
+		if got := w.Header().Get("Content-Disposition"); got != tt.want {
+			t.Errorf("Content-Disposition for %q = %s, want %s", tt.filename, got, tt.want)
+		}
+		if w.Body.String() != "a,b" {
+			t.Errorf("body = %q", w.Body)
+		}
+	}
+}
+
+func TestRedirect(t *testing.T) {
+	r := New()
+	r.GET("/old", func(c *Context) { c.Redirect(http.StatusMovedPermanently, "/new") })
+	r.POST("/items", func(c *Context) { c.Redirect(http.StatusCreated, "/items/1") })
+	w := performRequest(r, http.MethodGet, "/old", nil)
+	if w.Code != http.StatusMovedPermanently || w.Header().Get("Location") != "/new" {
+		t.Fatalf("redirect = %d %v", w.Code, w.Header())
+	}
+	if w := performRequest(r, http.MethodPost, "/items", nil); w.Code != http.StatusCreated || w.Header().Get("Location") != "/items/1" {
+		t.Fatalf("201 redirect = %d %v", w.Code, w.Header())
+	}
+
+	for _, code := range []int{http.StatusOK, http.StatusNotModified, http.StatusUseProxy, 306, http.StatusBadRequest} {
+		c := newContext(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+		mustPanic(t, "cannot redirect", func() { c.Redirect(code, "/") })
+	}
+}
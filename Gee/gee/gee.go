@@ -1,11 +1,11 @@
 package gee
 
 import (
-	"log"
+	"context"
+	"html/template"
 	"net/http"
-	"path"
-	"strings"
-	"text/template"
+	"net/netip"
+	"sync/atomic"
 )
 
 type Handlerfunc func(*Context)
@@ -13,11 +13,25 @@ type Handlerfunc func(*Context)
 // Engine 类型定义了一个引擎结构体。
 // 它包含一个路由器(router)、一个RouteGroup指针、以及一个存储所有路由分组的切片(groups)。
 type Engine struct {
-	router        *router            // 负责路径匹配和处理的路由器
-	*RouteGroup                      // 基础路由分组，提供路由创建的快捷方法
-	groups        []*RouteGroup      // 存储所有路由分组，用于管理路由的组织结构
-	htmlTemplates *template.Template // 用于渲染HTML模板的模板引擎
-	funcMap       template.FuncMap   // 用于模板渲染时的函数映射
+	router                 *router              // 负责路径匹配和处理的路由器
+	*RouteGroup                                 // 基础路由分组，提供路由创建的快捷方法
+	groups                 []*RouteGroup        // 存储所有路由分组，用于管理路由的组织结构
+	htmlRender             *htmlRender          // 用于渲染HTML模板的模板引擎
+	funcMap                template.FuncMap     // 用于模板渲染时的函数映射
+	TemplateReload         bool                 // 为true时每次渲染前检查模板文件是否变化并重新解析，便于开发调试
+	ServerConfig           ServerConfig         // 启动服务器时使用的超时和关闭配置，通过Host创建的子引擎使用所属引擎的配置
+	HandleMethodNotAllowed bool                 // 为true时路径存在但方法不匹配的请求返回405并携带Allow头，调用NoMethod时自动开启
+	life                   *lifecycle           // 运行中的服务器、关闭钩子和就绪状态，通过Host创建的子引擎与所属引擎共用
+	parent                 *Engine              // 通过Host创建的子引擎指向所属的引擎
+	hosts                  hostTable            // 按Host请求头分发请求的子引擎
+	routes                 []RouteInfo          // 按注册顺序记录的全部路由
+	docs                   map[string]Operation // 路由的OpenAPI注解，键为 method-pattern
+	bodyLimits             map[string]int64     // 通过RouteBodyLimit设置的单个路由的请求体限制，键为 method-pattern
+	MaxBodyBytes           int64                // 请求体的最大字节数，超出时返回413，小于0时不限制；为0时子引擎使用所属引擎的设置，顶层引擎不限制。分组和路由可以通过BodyLimit和RouteBodyLimit覆盖
+	trustedProxies         []netip.Prefix       // 可信代理的网段，通过SetTrustedProxies设置
+	RemoteIPHeaders        []string             // 对端是可信代理时，ClientIP按顺序读取的请求头，默认为X-Forwarded-For和X-Real-IP
+	TrustedPlatform        string               // 由部署平台设置、直接信任的客户端IP请求头，例如PlatformCloudflare
+	panicWarned            atomic.Bool          // DebugMode下已经提示过panic没有被恢复
 }
 
 // RouteGroup 类型定义了一个路由分组结构体。
@@ -28,11 +42,19 @@ type RouteGroup struct {
 	middleware []Handlerfunc // 路由分组的共同中间件函数列表
 	parent     *RouteGroup   // 父路由分组，用于实现嵌套分组
 	engine     *Engine       // 所属的引擎实例
+	noRoute    []Handlerfunc // 分组内没有匹配路由时执行的处理函数
+	noMethod   []Handlerfunc // 分组内路径存在但请求方法不匹配时执行的处理函数
+	bodyLimit  int64         // 分组内请求体的最大字节数，为0时使用上层设置，小于0表示不限制
 }
 
 // 创建一个引擎结构体
 func New() *Engine {
-	engine := &Engine{router: newRouter()}
+	engine := &Engine{
+		router:          newRouter(),
+		life:            &lifecycle{},
+		TemplateReload:  IsDebugging(),
+		RemoteIPHeaders: append([]string(nil), defaultRemoteIPHeaders...),
+	}
 	engine.RouteGroup = &RouteGroup{engine: engine}
 	engine.groups = []*RouteGroup{engine.RouteGroup}
 	return engine
@@ -40,20 +62,6 @@ func New() *Engine {
 func (group *RouteGroup) Use(middleware ...Handlerfunc) {
 	group.middleware = append(group.middleware, middleware...)
 }
-func (engine *Engine) SetFuncMap(funcMap template.FuncMap) {
-	engine.funcMap = funcMap
-}
-
-// LoadHTMLGlob函数用于根据指定的模式加载HTML模板。
-//
-// 参数:
-// pattern - 一个字符串，用于指定要加载的模板的模式。
-//
-// 此函数没有返回值。
-func (engine *Engine) LoadHTMLGlob(pattern string) {
-	// 使用指定的模式加载所有的HTML模板，并将它们存储在engine的htmlTemplates字段中。
-	engine.htmlTemplates = template.Must(template.New("").Funcs(engine.funcMap).ParseGlob(pattern)) //这个函数用于解析一个目录下的所有模板文件，并将它们加载到一个模板集中。
-}
 
 // Group 创建一个新的路由分组，该分组继承当前分组的前缀和引擎，
 // 同时添加一个新的前缀。
@@ -80,7 +88,8 @@ func (group *RouteGroup) Group(prefix string) *RouteGroup {
 }
 func (group *RouteGroup) addRoute(method string, comp string, handler Handlerfunc) {
 	pattern := group.prefix + comp
-	log.Printf("Route %4s - %s", method, pattern)
+	debugPrintf("Route %4s - %s", method, pattern)
+	group.engine.routes = append(group.engine.routes, RouteInfo{Method: method, Path: pattern})
 	group.engine.router.addRoute(method, pattern, handler)
 }
 
@@ -89,29 +98,46 @@ func (group *RouteGroup) addRoute(method string, comp string, handler Handlerfun
 // 参数 w 用于向客户端发送响应；
 // 参数 req 代表客户端的请求。
 func (engine *Engine) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	// 如果Host请求头匹配了某个子引擎，则交给该子引擎处理
+	if sub, r := engine.hosts.match(req); sub != nil {
+		sub.ServeHTTP(w, r)
+		return
+	}
+	if IsDebugging() {
+		defer engine.warnUnrecovered()
+	}
+
 	var middlewares []Handlerfunc // 定义一个中间件切片，用于存储匹配到的中间件
 
-	// 遍历所有处理组，检查请求的 URL 是否以处理组的前缀开头
+	// 遍历所有处理组，检查请求的 URL 是否位于处理组的前缀之下
 	for _, group := range engine.groups {
-		// 用于判断一个字符串（req.URL.Path 在本例中）
-		// 是否以指定的前缀（group.prefix 在本例中）开始。
-		if strings.HasPrefix(req.URL.Path, group.prefix) {
+		// 按路径段比较请求路径（req.URL.Path）与分组前缀（group.prefix），
+		// 前缀中的 ":param" 可以匹配任意一段路径。
+		if group.inGroup(req.URL.Path) {
 			// 如果匹配成功，则将该处理组的中间件追加到中间件切片中
 			middlewares = append(middlewares, group.middleware...)
 		}
-		c := newContext(w, req)
-		c.handler = middlewares
-		c.engine = engine
-		engine.router.handle(c)
-
 	}
 
 	// 创建一个新的上下文，用于处理当前请求
 	c := newContext(w, req)
 	// 设置上下文的处理者为匹配到的中间件链
 	c.handler = middlewares
+	c.engine = engine
+	// 查找匹配的路由
+	engine.router.match(c)
+	// 为请求体设置大小限制，Content-Length已经超出限制时直接返回413
+	if !c.limitBody(w, engine.maxBodyBytes(c.routeKey, req.URL.Path)) {
+		c.handler = append(c.handler, entityTooLarge)
+		c.Next()
+		return
+	}
 	// 使用路由器处理请求
 	engine.router.handle(c)
+	// 读取请求体时超出了限制，而处理函数没有写出响应
+	if c.bodyTooLarge && !c.Writer.Written() {
+		entityTooLarge(c)
+	}
 }
 
 // 定义GET方法
@@ -124,42 +150,26 @@ func (group *RouteGroup) POST(patten string, handler Handlerfunc) {
 	group.addRoute("POST", patten, handler)
 }
 
-// 启动服务器
-func (engine *Engine) Run(addr string) (err error) {
-	return http.ListenAndServe(addr, engine)
+// Handle 使用任意请求方法注册路由
+func (group *RouteGroup) Handle(method string, patten string, handler Handlerfunc) {
+	group.addRoute(method, patten, handler)
 }
 
-// createStaticHandler 创建一个处理静态文件请求的Handler。
-// relativePath 相对于路由组前缀的相对路径。
-// fs 是实现http.FileSystem接口的文件系统。
-// 返回一个Handlerfunc，用于处理静态文件请求。
-func (group *RouteGroup) createStaticHandler(relativePath string, fs http.FileSystem) Handlerfunc {
-	// 将路由组前缀和相对路径合并，得到服务静态文件的绝对路径。
-	absolutePath := path.Join(group.prefix, relativePath)
-	// 使用http.StripPrefix处理请求的路径，确保能够正确地从文件系统中获取文件。
-	fileServer := http.StripPrefix(absolutePath, http.FileServer(fs))
-	// 返回处理静态文件请求的函数。
-	return func(c *Context) {
-		// 从请求中获取文件路径参数。
-		file := c.Param("filepath")
-		// 尝试打开文件，如果失败则返回404状态码。
-		if _, err := fs.Open(file); err != nil {
-			c.Status(http.StatusNotFound)
-			return
-		}
-		// 使用fileServer处理请求并响应。
-		fileServer.ServeHTTP(c.Writer, c.Req)
+// anyMethods 是Any注册路由时使用的全部请求方法
+var anyMethods = []string{
+	http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete,
+	http.MethodHead, http.MethodOptions, http.MethodConnect, http.MethodTrace,
+}
+
+// Any 为所有请求方法注册同一个处理函数
+func (group *RouteGroup) Any(patten string, handler Handlerfunc) {
+	for _, method := range anyMethods {
+		group.addRoute(method, patten, handler)
 	}
 }
 
-// Static 注册一个处理静态文件请求的路由。
-// relativePath 是相对于应用根路径的静态资源路径。
-// root 是静态资源在文件系统中的根目录。
-func (group *RouteGroup) Static(relativePath string, root string) {
-	// 创建处理静态文件请求的handler。
-	handler := group.createStaticHandler(relativePath, http.Dir(root))
-	// 构建匹配静态文件请求的URL模式。
-	urlPattern := path.Join(relativePath, "/*filepath")
-	// 使用GET方法注册处理静态文件请求的路由。
-	group.GET(urlPattern, handler)
+// 启动服务器，阻塞直到服务器关闭。
+// 开启ServerConfig.HandleSignals后，收到SIGINT或SIGTERM时会优雅关闭并返回nil。
+func (engine *Engine) Run(addr string) (err error) {
+	return engine.RunContext(context.Background(), addr)
 }
@@ -0,0 +1,224 @@
+package gee
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Checker 是一个具名的健康检查。Check返回nil表示健康，ctx在超时后会被取消，不受请求取消的影响。
+type Checker interface {
+	Name() string
+	Check(ctx context.Context) error
+}
+
+// checkFunc 把普通函数适配为Checker。
+type checkFunc struct {
+	name string
+	fn   func(ctx context.Context) error
+}
+
+func (c checkFunc) Name() string                    { return c.name }
+func (c checkFunc) Check(ctx context.Context) error { return c.fn(ctx) }
+
+// CheckFunc 用函数创建一个名为name的健康检查。
+func CheckFunc(name string, fn func(ctx context.Context) error) Checker {
+	return checkFunc{name: name, fn: fn}
+}
+
+// Availabler 是可以报告自身是否可用的对象，例如LGRPC的Client。
+type Availabler interface {
+	IsAvailable() bool
+}
+
+// AvailableCheck 创建一个检查target.IsAvailable()的健康检查，
+// 可以用于探测LGRPC客户端等长连接是否仍然可用。
+func AvailableCheck(name string, target Availabler) Checker {
+	return CheckFunc(name, func(ctx context.Context) error {
+		if !target.IsAvailable() {
+			return errors.New("not available")
+		}
+		return nil
+	})
+}
+
+// livenessCheck 标记一个检查同时用于存活检查。
+type livenessCheck struct {
+	Checker
+}
+
+// Liveness 把检查标记为存活检查。存活检查失败意味着进程需要重启，
+// 因此只应包含进程自身的状态（例如死锁检测），不应包含数据库等外部依赖。
+// 存活检查同样会出现在就绪检查和完整报告中。
+func Liveness(c Checker) Checker {
+	return livenessCheck{c}
+}
+
+// HealthConfig 用于配置健康检查端点。
+type HealthConfig struct {
+	// Timeout 单个检查的超时时间，小于等于0时使用5秒。
+	Timeout time.Duration
+	// CacheTTL 检查结果的缓存时间，在此期间重复请求直接返回缓存的结果，为0时不缓存。
+	CacheTTL time.Duration
+}
+
+// defaultHealthTimeout 是单个健康检查默认的超时时间。
+const defaultHealthTimeout = 5 * time.Second
+
+// 健康检查结果中的状态值
+const (
+	HealthPass = "pass"
+	HealthFail = "fail"
+)
+
+// shutdownCheck 是就绪检查在引擎关闭时报告失败使用的检查名，用户的检查不能使用这个名字。
+const shutdownCheck = "shutdown"
+
+// CheckResult 是单个检查的结果。
+type CheckResult struct {
+	Status   string `json:"status"`
+	Duration string `json:"duration"`
+	Error    string `json:"error,omitempty"`
+}
+
+// HealthReport 是健康检查端点返回的JSON内容。
+type HealthReport struct {
+	Status string                 `json:"status"`
+	Checks map[string]CheckResult `json:"checks,omitempty"`
+}
+
+// cachedCheck 包装一个检查，负责超时控制和结果缓存。
+type cachedCheck struct {
+	checker Checker
+	timeout time.Duration
+	ttl     time.Duration
+	mu      sync.Mutex // 同一时间只执行一次检查，并发的请求等待并共享结果
+	result  CheckResult
+	expires time.Time
+}
+
+// run 返回检查结果，缓存未过期时直接返回缓存。
+func (c *cachedCheck) run(ctx context.Context) CheckResult {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.ttl > 0 && time.Now().Before(c.expires) {
+		return c.result
+	}
+	start := time.Now()
+	err := c.check(ctx)
+	res := CheckResult{Status: HealthPass, Duration: time.Since(start).String()}
+	if err != nil {
+		res.Status = HealthFail
+		res.Error = err.Error()
+	}
+	c.result = res
+	c.expires = time.Now().Add(c.ttl)
+	return res
+}
+
+// check 在超时时间内执行检查，检查中的panic被视为失败。
+// 检查使用与请求分离的context：客户端断开连接不会取消检查，否则取消造成的失败会被缓存并返回给其他探针。
+func (c *cachedCheck) check(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), c.timeout)
+	defer cancel()
+	done := make(chan error, 1)
+	go func() {
+		defer func() {
+			if err := recover(); err != nil {
+				done <- fmt.Errorf("panic: %v", err)
+			}
+		}()
+		done <- c.checker.Check(ctx)
+	}()
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return fmt.Errorf("timed out after %s", c.timeout)
+	}
+}
+
+// Health 在path下注册健康检查端点，使用默认配置：
+//   - GET path：执行全部检查
+//   - GET path/ready：就绪检查，执行全部检查，引擎开始关闭后直接失败
+//   - GET path/live：存活检查，只执行通过Liveness标记的检查
+//
+// 所有检查都通过时返回200，否则返回503，响应体为HealthReport。
+// 检查名"shutdown"保留给就绪检查报告关闭状态，注册同名检查会panic。
+// 在Host子引擎上注册时，就绪状态跟随顶层引擎的关闭流程。
+func (engine *Engine) Health(path string, checks ...Checker) {
+	engine.HealthWithConfig(path, HealthConfig{}, checks...)
+}
+
+// HealthWithConfig 按照给定的配置注册健康检查端点，参见Health。
+func (engine *Engine) HealthWithConfig(path string, conf HealthConfig, checks ...Checker) {
+	timeout := conf.Timeout
+	if timeout <= 0 {
+		timeout = defaultHealthTimeout
+	}
+	names := make(map[string]bool)
+	var all, live []*cachedCheck
+	for _, checker := range checks {
+		name := checker.Name()
+		if name == shutdownCheck {
+			panic("gee: health check name '" + shutdownCheck + "' is reserved")
+		}
+		if names[name] {
+			panic("gee: duplicate health check '" + name + "'")
+		}
+		names[name] = true
+		_, isLive := checker.(livenessCheck)
+		cc := &cachedCheck{checker: checker, timeout: timeout, ttl: conf.CacheTTL}
+		all = append(all, cc)
+		if isLive {
+			live = append(live, cc)
+		}
+	}
+
+	path = strings.TrimRight(path, "/")
+	full := path
+	if full == "" {
+		full = "/"
+	}
+	engine.GET(full, engine.healthHandler(all, true))
+	engine.GET(path+"/ready", engine.healthHandler(all, true))
+	engine.GET(path+"/live", engine.healthHandler(live, false))
+}
+
+// healthHandler 并发执行checks并返回报告。readiness为true时，引擎处于关闭过程中会直接报告失败。
+func (engine *Engine) healthHandler(checks []*cachedCheck, readiness bool) Handlerfunc {
+	return func(c *Context) {
+		report := HealthReport{Status: HealthPass, Checks: make(map[string]CheckResult, len(checks)+1)}
+		if readiness && !engine.root().Ready() {
+			report.Checks[shutdownCheck] = CheckResult{Status: HealthFail, Duration: "0s", Error: "server is shutting down"}
+		}
+
+		results := make([]CheckResult, len(checks))
+		var wg sync.WaitGroup
+		for i, check := range checks {
+			wg.Add(1)
+			go func(i int, check *cachedCheck) {
+				defer wg.Done()
+				results[i] = check.run(c.Req.Context())
+			}(i, check)
+		}
+		wg.Wait()
+		for i, check := range checks {
+			report.Checks[check.checker.Name()] = results[i]
+		}
+
+		code := http.StatusOK
+		for _, res := range report.Checks {
+			if res.Status != HealthPass {
+				report.Status = HealthFail
+				code = http.StatusServiceUnavailable
+			}
+		}
+		c.SetHeader("Cache-Control", "no-store")
+		c.JSON(code, report)
+	}
+}
@@ -0,0 +1,152 @@
+package gee
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type availability bool
+
+func (a availability) IsAvailable() bool { return bool(a) }
+
+// healthReport 解析健康检查端点返回的报告。
+func healthReport(t *testing.T, body []byte) HealthReport {
+	t.Helper()
+	var report HealthReport
+	if err := json.Unmarshal(body, &report); err != nil {
+		t.Fatalf("invalid report %s: %v", body, err)
+	}
+	return report
+}
+
+// getHealth 请求健康检查端点，返回状态码和报告。
+func getHealth(t *testing.T, r http.Handler, path string) (int, HealthReport) {
+	t.Helper()
+	w := performRequest(r, http.MethodGet, path, nil)
+	if cc := w.Header().Get("Cache-Control"); cc != "no-store" {
+		t.Errorf("GET %s Cache-Control = %q", path, cc)
+	}
+	return w.Code, healthReport(t, w.Body.Bytes())
+}
+
+func TestHealthEndpoints(t *testing.T) {
+	var calls atomic.Int32
+	r := New()
+	r.HealthWithConfig("/healthz", HealthConfig{Timeout: 50 * time.Millisecond, CacheTTL: time.Minute},
+		Liveness(CheckFunc("self", func(ctx context.Context) error {
+			calls.Add(1)
+			return nil
+		})),
+		AvailableCheck("rpc", availability(true)),
+		AvailableCheck("db", availability(false)),
+		CheckFunc("slow", func(ctx context.Context) error {
+			<-ctx.Done()
+			return ctx.Err()
+		}),
+		CheckFunc("panics", func(ctx context.Context) error { panic("boom") }),
+	)
+
+	code, report := getHealth(t, r, "/healthz/live")
+	if code != http.StatusOK || report.Status != HealthPass || len(report.Checks) != 1 {
+		t.Fatalf("live = %d %+v", code, report)
+	}
+
+	code, report = getHealth(t, r, "/healthz/ready")
+	if code != http.StatusServiceUnavailable || report.Status != HealthFail {
+		t.Fatalf("ready = %d %+v", code, report)
+	}
+	for name, want := range map[string]string{
+		"rpc":    "",
+		"db":     "not available",
+		"slow":   "timed out after 50ms",
+		"panics": "panic: boom",
+	} {
+		if got := report.Checks[name].Error; got != want {
+			t.Errorf("check %s error = %q, want %q", name, got, want)
+		}
+	}
+
+	getHealth(t, r, "/healthz")
+	if n := calls.Load(); n != 1 {
+		t.Fatalf("cached check ran %d times, want 1", n)
+	}
+}
+
+func TestHealthCheckIgnoresClientCancel(t *testing.T) {
+	started := make(chan struct{})
+	r := New()
+	r.HealthWithConfig("/healthz", HealthConfig{Timeout: time.Second, CacheTTL: time.Minute},
+		CheckFunc("db", func(ctx context.Context) error {
+			close(started)
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(20 * time.Millisecond):
+				return nil
+			}
+		}),
+	)
+
+	// 第一个探针在检查执行期间断开连接
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		<-started
+		cancel()
+	}()
+	req := httptest.NewRequest(http.MethodGet, "/healthz", nil).WithContext(ctx)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if report := healthReport(t, w.Body.Bytes()); report.Checks["db"].Status != HealthPass {
+		t.Fatalf("canceled probe = %+v, want the check to finish", report)
+	}
+	if code, report := getHealth(t, r, "/healthz"); code != http.StatusOK {
+		t.Fatalf("next probe = %d %+v", code, report)
+	}
+}
+
+func TestHealthReadinessDuringShutdown(t *testing.T) {
+	r := New()
+	r.Health("/healthz", CheckFunc("ok", func(ctx context.Context) error { return nil }))
+	api := r.Host("api.example.com")
+	api.Health("/", CheckFunc("ok", func(ctx context.Context) error { return nil }))
+
+	if code, _ := getHealth(t, r, "/healthz/ready"); code != http.StatusOK {
+		t.Fatalf("ready before shutdown = %d", code)
+	}
+	if w := hostRequest(r, http.MethodGet, "api.example.com", "/ready"); w.Code != http.StatusOK {
+		t.Fatalf("host ready before shutdown = %d %s", w.Code, w.Body)
+	}
+	if err := r.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	code, report := getHealth(t, r, "/healthz/ready")
+	if code != http.StatusServiceUnavailable || report.Checks["shutdown"].Error != "server is shutting down" {
+		t.Fatalf("ready after shutdown = %d %+v", code, report)
+	}
+	w := hostRequest(r, http.MethodGet, "api.example.com", "/ready")
+	if report := healthReport(t, w.Body.Bytes()); w.Code != http.StatusServiceUnavailable || report.Checks["shutdown"].Status != HealthFail {
+		t.Fatalf("host ready after shutdown = %d %s", w.Code, w.Body)
+	}
+	if code, _ := getHealth(t, r, "/healthz/live"); code != http.StatusOK {
+		t.Fatalf("live after shutdown = %d", code)
+	}
+}
+
+func TestHealthCheckNames(t *testing.T) {
+	ok := func(ctx context.Context) error { return nil }
+	mustPanic(t, "is reserved", func() {
+		New().Health("/healthz", CheckFunc("shutdown", ok))
+	})
+	mustPanic(t, "duplicate health check 'db'", func() {
+		New().Health("/healthz", CheckFunc("db", ok), Liveness(CheckFunc("db", func(ctx context.Context) error {
+			return errors.New("down")
+		})))
+	})
+}
@@ -0,0 +1,149 @@
+package gee
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// hostRoute 是一个带参数或通配符的主机名模式。
+type hostRoute struct {
+	labels []string // labels 为按"."拆分的主机名各段
+	engine *Engine
+}
+
+// hostTable 保存按主机名分发的子引擎。
+// 精确匹配优先；其余模式按注册顺序匹配，":name"捕获一段主机名，"*"匹配任意一段。
+type hostTable struct {
+	mu       sync.RWMutex
+	exact    map[string]*Engine
+	patterns []*hostRoute
+}
+
+// Host 返回处理指定主机名的子引擎，不存在时创建。
+// 子引擎拥有独立的路由表和中间件；没有匹配任何主机名的请求由当前引擎处理，作为默认主机。
+// 子引擎与所属引擎共用生命周期（Ready、Shutdown、OnShutdown）、ServerConfig、可信代理配置和模板，
+// 所属引擎开启HandleMethodNotAllowed时对子引擎同样生效。
+// 模式中的 ":name" 会捕获对应的一段主机名，例如 ":tenant.example.com"，
+// 捕获的值可以在子引擎的处理函数中通过c.Param("tenant")读取。
+// 匹配时忽略大小写和端口号，模式中的端口号（例如 "api.example.com:8080"）会被去掉。
+func (engine *Engine) Host(pattern string) *Engine {
+	pattern = strings.ToLower(strings.TrimSuffix(stripHostPort(pattern), "."))
+	t := &engine.hosts
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if sub, ok := t.exact[pattern]; ok {
+		return sub
+	}
+	labels := strings.Split(pattern, ".")
+	for _, hr := range t.patterns {
+		if strings.Join(hr.labels, ".") == pattern {
+			return hr.engine
+		}
+	}
+
+	sub := New()
+	sub.parent = engine
+	sub.life = engine.life
+	sub.funcMap = engine.funcMap
+	if !strings.ContainsAny(pattern, ":*") {
+		if t.exact == nil {
+			t.exact = make(map[string]*Engine)
+		}
+		t.exact[pattern] = sub
+	} else {
+		t.patterns = append(t.patterns, &hostRoute{labels: labels, engine: sub})
+	}
+	return sub
+}
+
+// stripHostPort 去掉主机名模式结尾的端口号。只有最后一个":"之后全是数字、
+// 并且":"不在一段主机名的开头时才视为端口，":name"仍然表示捕获参数。
+func stripHostPort(pattern string) string {
+	i := strings.LastIndexByte(pattern, ':')
+	if i <= 0 || i == len(pattern)-1 || pattern[i-1] == '.' {
+		return pattern
+	}
+	for _, ch := range pattern[i+1:] {
+		if ch < '0' || ch > '9' {
+			return pattern
+		}
+	}
+	return pattern[:i]
+}
+
+// root 返回最上层的引擎，通过Host创建的子引擎从中读取共用的配置。
+func (engine *Engine) root() *Engine {
+	for engine.parent != nil {
+		engine = engine.parent
+	}
+	return engine
+}
+
+// methodNotAllowed 报告是否区分405，子引擎或任一上层引擎开启即生效。
+func (engine *Engine) methodNotAllowed() bool {
+	for ; engine != nil; engine = engine.parent {
+		if engine.HandleMethodNotAllowed {
+			return true
+		}
+	}
+	return false
+}
+
+// match 根据请求的Host找到对应的子引擎。
+// 模式中捕获的参数会合并到请求context中，由子引擎写入Context.Params。
+func (t *hostTable) match(req *http.Request) (*Engine, *http.Request) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	if len(t.exact) == 0 && len(t.patterns) == 0 {
+		return nil, req
+	}
+	host := req.Host
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	if sub, ok := t.exact[host]; ok {
+		return sub, req
+	}
+	labels := strings.Split(host, ".")
+	for _, hr := range t.patterns {
+		params, ok := hr.match(labels)
+		if !ok {
+			continue
+		}
+		if len(params) > 0 {
+			for k, v := range RequestParams(req) {
+				if _, exists := params[k]; !exists {
+					params[k] = v
+				}
+			}
+			req = req.WithContext(context.WithValue(req.Context(), paramsKey{}, params))
+		}
+		return hr.engine, req
+	}
+	return nil, req
+}
+
+// match 逐段比较主机名，返回捕获的参数。
+func (hr *hostRoute) match(labels []string) (map[string]string, bool) {
+	if len(labels) != len(hr.labels) {
+		return nil, false
+	}
+	var params map[string]string
+	for i, label := range hr.labels {
+		switch {
+		case label == "*":
+		case strings.HasPrefix(label, ":"):
+			if params == nil {
+				params = make(map[string]string)
+			}
+			params[label[1:]] = labels[i]
+		case label != labels[i]:
+			return nil, false
+		}
+	}
+	return params, true
+}
@@ -0,0 +1,77 @@
+package gee
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// hostRequest 以指定的Host请求头访问path。
+func hostRequest(handler http.Handler, method, host, path string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, nil)
+	req.Host = host
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	return w
+}
+
+func TestHostRouting(t *testing.T) {
+	r := New()
+	r.GET("/", func(c *Context) { c.String(http.StatusOK, "default") })
+	api := r.Host("api.example.com")
+	api.Use(func(c *Context) { c.SetHeader("X-Api", "1"); c.Next() })
+	api.GET("/", func(c *Context) { c.String(http.StatusOK, "api") })
+	tenant := r.Host(":tenant.example.com")
+	tenant.GET("/", func(c *Context) { c.String(http.StatusOK, "tenant %s", c.Param("tenant")) })
+	r.Host("*.cdn.example.com").GET("/", func(c *Context) { c.String(http.StatusOK, "cdn") })
+
+	if r.Host("API.example.com.") != api || r.Host("api.example.com:8080") != api {
+		t.Fatal("Host did not return the existing engine")
+	}
+	r.Host(":tenant.example.org:8443").GET("/", func(c *Context) { c.String(http.StatusOK, "org %s", c.Param("tenant")) })
+	tests := []struct {
+		host, body string
+	}{
+		{"api.example.com:8080", "api"},
+		{"ACME.example.com", "tenant acme"},
+		{"x.cdn.example.com", "cdn"},
+		{"a.b.example.com", "default"},
+		{"acme.example.org:443", "org acme"},
+		{"other.org", "default"},
+	}
+	for _, tt := range tests {
+		if w := hostRequest(r, http.MethodGet, tt.host, "/"); w.Body.String() != tt.body {
+			t.Errorf("Host %s = %q, want %q", tt.host, w.Body.String(), tt.body)
+		}
+	}
+	if w := hostRequest(r, http.MethodGet, "other.org", "/"); w.Header().Get("X-Api") != "" {
+		t.Fatal("host middleware ran for the default host")
+	}
+}
+
+func TestHostInheritsEngineSettings(t *testing.T) {
+	r := New()
+	r.ServerConfig.ReadHeaderTimeout = time.Second
+	api := r.Host("api.example.com")
+	api.GET("/users", func(c *Context) {})
+	// 子引擎创建之后再修改的设置同样生效
+	r.HandleMethodNotAllowed = true
+
+	if w := hostRequest(r, http.MethodPost, "api.example.com", "/users"); w.Code != http.StatusMethodNotAllowed {
+		t.Fatalf("status = %d, want 405", w.Code)
+	}
+	if srv := api.newServer(":0"); srv.ReadHeaderTimeout != time.Second {
+		t.Fatalf("sub-engine server ReadHeaderTimeout = %v", srv.ReadHeaderTimeout)
+	}
+
+	hookRan := false
+	api.OnShutdown(func(ctx context.Context) error { hookRan = true; return nil })
+	if err := r.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if api.Ready() || !hookRan {
+		t.Fatalf("sub-engine ready = %v, hook ran = %v after the parent shut down", api.Ready(), hookRan)
+	}
+}
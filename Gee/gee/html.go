@@ -0,0 +1,298 @@
+package gee
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// TemplateConfig 用于配置基于布局的HTML模板。
+// 每个页面模板都会与布局和局部模板组成独立的模板集，
+// 页面通过 {{define "name"}} 覆盖布局中的 {{block "name" .}}。
+type TemplateConfig struct {
+	// FS 模板所在的文件系统，为nil时从本地磁盘读取。
+	FS fs.FS
+	// Layout 布局文件，为空时直接渲染页面模板本身。
+	Layout string
+	// Partials 局部模板的glob模式，其中通过define定义的模板可以在所有页面中引用。
+	Partials []string
+	// Pages 页面模板的glob模式，渲染时以文件名作为模板名，不同目录下的页面文件名不能相同。
+	Pages []string
+}
+
+// templateSource 抽象了本地磁盘和fs.FS两种模板来源。
+type templateSource struct {
+	fsys fs.FS
+}
+
+func (s templateSource) glob(pattern string) ([]string, error) {
+	if s.fsys == nil {
+		return filepath.Glob(pattern)
+	}
+	return fs.Glob(s.fsys, pattern)
+}
+
+func (s templateSource) stat(name string) (fs.FileInfo, error) {
+	if s.fsys == nil {
+		return os.Stat(name)
+	}
+	return fs.Stat(s.fsys, name)
+}
+
+func (s templateSource) read(name string) ([]byte, error) {
+	if s.fsys == nil {
+		return os.ReadFile(name)
+	}
+	return fs.ReadFile(s.fsys, name)
+}
+
+// base 返回模板名，与template.ParseFiles的约定一致，使用文件名作为模板名。
+func (s templateSource) base(name string) string {
+	if s.fsys == nil {
+		return filepath.Base(name)
+	}
+	return path.Base(name)
+}
+
+// htmlRender 保存解析好的模板，并在开启热加载时检测文件变化后重新解析。
+type htmlRender struct {
+	mu     sync.RWMutex
+	src    templateSource
+	conf   TemplateConfig
+	single bool                          // single 为true时所有文件解析到同一个模板集中
+	files  []string                      // files 为单模板集模式下的glob模式或文件列表
+	glob   bool                          // glob 表示files是否为glob模式
+	fixed  bool                          // fixed 为true时模板由调用方直接提供，不支持热加载
+	funcs  template.FuncMap              // funcs 为解析时注册的函数映射
+	root   *template.Template            // root 为单模板集，或布局模式下的公共模板集
+	pages  map[string]*template.Template // pages 为布局模式下每个页面独立的模板集
+	stamps map[string]time.Time          // stamps 记录解析时各文件的修改时间
+}
+
+// expand 展开glob模式，得到需要解析的文件列表。
+func (r *htmlRender) expand(patterns []string) ([]string, error) {
+	if !r.glob {
+		return patterns, nil
+	}
+	var files []string
+	for _, pattern := range patterns {
+		matches, err := r.src.glob(pattern)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, matches...)
+	}
+	return files, nil
+}
+
+// parseInto 将文件逐个解析到模板集t中。
+func (r *htmlRender) parseInto(t *template.Template, files []string, stamps map[string]time.Time) error {
+	for _, file := range files {
+		data, err := r.src.read(file)
+		if err != nil {
+			return err
+		}
+		if info, err := r.src.stat(file); err == nil {
+			stamps[file] = info.ModTime()
+		}
+		if _, err := t.New(r.src.base(file)).Parse(string(data)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sources 展开并去重需要解析的模板文件。布局模式下shared为布局和局部模板，pages为页面模板；
+// 已经作为布局或局部模板的文件即使被Pages的glob匹配到，也不会再作为页面解析。
+func (r *htmlRender) sources() (shared, pages []string, err error) {
+	seen := make(map[string]bool)
+	add := func(dst, files []string) []string {
+		for _, file := range files {
+			if !seen[file] {
+				seen[file] = true
+				dst = append(dst, file)
+			}
+		}
+		return dst
+	}
+	if r.single {
+		files, err := r.expand(r.files)
+		if err != nil {
+			return nil, nil, err
+		}
+		return add(nil, files), nil, nil
+	}
+	if r.conf.Layout != "" {
+		shared = add(shared, []string{r.conf.Layout})
+	}
+	partials, err := r.expand(r.conf.Partials)
+	if err != nil {
+		return nil, nil, err
+	}
+	shared = add(shared, partials)
+	pageFiles, err := r.expand(r.conf.Pages)
+	if err != nil {
+		return nil, nil, err
+	}
+	return shared, add(nil, pageFiles), nil
+}
+
+// load 重新解析全部模板。
+func (r *htmlRender) load() error {
+	shared, pageFiles, err := r.sources()
+	if err != nil {
+		return err
+	}
+	stamps := make(map[string]time.Time)
+	root := template.New("").Funcs(r.funcs)
+	if r.single {
+		if len(shared) == 0 {
+			return fmt.Errorf("gee: no template files matched %v", r.files)
+		}
+		if err := r.parseInto(root, shared, stamps); err != nil {
+			return err
+		}
+		r.root, r.pages, r.stamps = root, nil, stamps
+		return nil
+	}
+
+	// 布局模式：先解析布局和局部模板，再为每个页面克隆一份公共模板集
+	if err := r.parseInto(root, shared, stamps); err != nil {
+		return err
+	}
+	pages := make(map[string]*template.Template, len(pageFiles))
+	for _, file := range pageFiles {
+		t, err := root.Clone()
+		if err != nil {
+			return err
+		}
+		name := r.src.base(file)
+		if _, ok := pages[name]; ok {
+			return fmt.Errorf("gee: duplicate template page name %q: %s", name, file)
+		}
+		if err := r.parseInto(t, []string{file}, stamps); err != nil {
+			return err
+		}
+		pages[name] = t
+	}
+	r.root, r.pages, r.stamps = root, pages, stamps
+	return nil
+}
+
+// changed 判断模板文件是否发生了变化，包括新增和删除的文件。
+func (r *htmlRender) changed() bool {
+	shared, pages, err := r.sources()
+	if err != nil {
+		return true
+	}
+	files := append(shared, pages...)
+	if len(files) != len(r.stamps) {
+		return true
+	}
+	for _, file := range files {
+		info, err := r.src.stat(file)
+		stamp, ok := r.stamps[file]
+		if err != nil || !ok || !info.ModTime().Equal(stamp) {
+			return true
+		}
+	}
+	return false
+}
+
+// render 执行名为name的模板，布局模式下name为页面文件名。
+func (r *htmlRender) render(w io.Writer, name string, data interface{}, reload bool) error {
+	if reload {
+		// 先在读锁下检查，只有文件确实变化时才获取写锁重新解析
+		r.mu.RLock()
+		changed := r.changed()
+		r.mu.RUnlock()
+		if changed {
+			r.mu.Lock()
+			if r.changed() {
+				if err := r.load(); err != nil {
+					r.mu.Unlock()
+					return err
+				}
+			}
+			r.mu.Unlock()
+		}
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if page, ok := r.pages[name]; ok {
+		if r.conf.Layout != "" {
+			return page.ExecuteTemplate(w, r.src.base(r.conf.Layout), data)
+		}
+		return page.ExecuteTemplate(w, name, data)
+	}
+	return r.root.ExecuteTemplate(w, name, data)
+}
+
+// setHTMLRender 解析模板并保存到引擎中，解析失败时panic。
+func (engine *Engine) setHTMLRender(r *htmlRender) {
+	r.funcs = engine.funcMap
+	if err := r.load(); err != nil {
+		panic(err)
+	}
+	engine.htmlRender = r
+}
+
+// SetFuncMap 设置模板渲染时可以使用的函数，需要在加载模板之前调用。
+func (engine *Engine) SetFuncMap(funcMap template.FuncMap) {
+	engine.funcMap = funcMap
+}
+
+// LoadHTMLGlob函数用于根据指定的模式加载HTML模板。
+// 模板使用html/template解析，输出时会根据上下文自动转义。
+//
+// 参数:
+// pattern - 一个字符串，用于指定要加载的模板的模式。
+//
+// 此函数没有返回值。
+func (engine *Engine) LoadHTMLGlob(pattern string) {
+	engine.setHTMLRender(&htmlRender{single: true, glob: true, files: []string{pattern}})
+}
+
+// LoadHTMLFiles 加载指定的模板文件，模板名为文件名。
+func (engine *Engine) LoadHTMLFiles(files ...string) {
+	engine.setHTMLRender(&htmlRender{single: true, files: files})
+}
+
+// LoadHTMLFS 从fs.FS（例如embed.FS）中按glob模式加载模板。
+func (engine *Engine) LoadHTMLFS(fsys fs.FS, patterns ...string) {
+	engine.setHTMLRender(&htmlRender{src: templateSource{fsys}, single: true, glob: true, files: patterns})
+}
+
+// LoadHTMLTemplates 加载基于布局的模板，页面可以覆盖布局中的block并引用局部模板。
+func (engine *Engine) LoadHTMLTemplates(conf TemplateConfig) {
+	engine.setHTMLRender(&htmlRender{src: templateSource{conf.FS}, glob: true, conf: conf})
+}
+
+// SetHTMLTemplate 直接使用已经解析好的模板集，此时不支持热加载。
+func (engine *Engine) SetHTMLTemplate(t *template.Template) {
+	engine.htmlRender = &htmlRender{single: true, fixed: true, root: t}
+}
+
+// renderHTML 渲染模板到缓冲区，避免渲染出错时已经写出了部分响应。
+func (engine *Engine) renderHTML(name string, data interface{}) ([]byte, error) {
+	// 通过Host创建的子引擎没有加载模板时，使用所属引擎的模板
+	for engine.htmlRender == nil && engine.parent != nil {
+		engine = engine.parent
+	}
+	if engine.htmlRender == nil {
+		return nil, fmt.Errorf("gee: html templates are not loaded")
+	}
+	var buf bytes.Buffer
+	reload := engine.TemplateReload && !engine.htmlRender.fixed
+	if err := engine.htmlRender.render(&buf, name, data, reload); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
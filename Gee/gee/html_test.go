@@ -0,0 +1,110 @@
+package gee
+
+import (
+	"html/template"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func TestLoadHTMLTemplates(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"layout.html":       `<html>{{block "title" .}}Default{{end}}|{{block "content" .}}{{end}}|{{template "nav" .}}</html>`,
+		"partials/nav.html": `{{define "nav"}}<nav>{{.}}</nav>{{end}}`,
+		"a.html":            `{{define "content"}}A:{{.}}{{end}}`,
+		"b.html":            `{{define "title"}}B{{end}}{{define "content"}}B{{end}}`,
+	})
+	r := New()
+	// 布局文件同时被Pages的glob匹配到
+	r.LoadHTMLTemplates(TemplateConfig{
+		Layout:   filepath.Join(dir, "layout.html"),
+		Partials: []string{filepath.Join(dir, "partials", "*.html")},
+		Pages:    []string{filepath.Join(dir, "*.html")},
+	})
+	r.GET("/a", func(c *Context) { c.HTML(http.StatusOK, "a.html", "<script>") })
+	r.GET("/b", func(c *Context) { c.HTML(http.StatusOK, "b.html", "x") })
+
+	w := performRequest(r, http.MethodGet, "/a", nil)
+	want := "<html>Default|A:&lt;script&gt;|<nav>&lt;script&gt;</nav></html>"
+	if w.Body.String() != want {
+		t.Fatalf("a.html = %q, want %q", w.Body.String(), want)
+	}
+	if w = performRequest(r, http.MethodGet, "/b", nil); w.Body.String() != "<html>B|B|<nav>x</nav></html>" {
+		t.Fatalf("b.html = %q", w.Body.String())
+	}
+	if _, ok := r.htmlRender.pages["layout.html"]; ok {
+		t.Fatal("layout was parsed as a page")
+	}
+	if r.htmlRender.changed() {
+		t.Fatal("templates reported as changed right after loading")
+	}
+}
+
+func TestLoadHTMLTemplatesDuplicatePage(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"users/index.html":  `users`,
+		"orders/index.html": `orders`,
+	})
+	defer func() {
+		err, _ := recover().(error)
+		if err == nil || !strings.Contains(err.Error(), "duplicate template page") {
+			t.Fatalf("recovered %v, want a duplicate page error", err)
+		}
+	}()
+	New().LoadHTMLTemplates(TemplateConfig{Pages: []string{filepath.Join(dir, "*", "index.html")}})
+}
+
+func TestHTMLTemplateReload(t *testing.T) {
+	dir := writeFiles(t, map[string]string{"index.html": `v1:{{.}}`})
+	r := New()
+	r.TemplateReload = true
+	r.LoadHTMLGlob(filepath.Join(dir, "*.html"))
+	r.GET("/", func(c *Context) { c.HTML(http.StatusOK, "index.html", "x") })
+
+	if w := performRequest(r, http.MethodGet, "/", nil); w.Body.String() != "v1:x" {
+		t.Fatalf("body = %q", w.Body.String())
+	}
+	file := filepath.Join(dir, "index.html")
+	if err := os.WriteFile(file, []byte(`v2:{{.}}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	future := time.Now().Add(time.Minute)
+	if err := os.Chtimes(file, future, future); err != nil {
+		t.Fatal(err)
+	}
+	if w := performRequest(r, http.MethodGet, "/", nil); w.Body.String() != "v2:x" {
+		t.Fatalf("after change body = %q", w.Body.String())
+	}
+}
+
+func TestLoadHTMLFSAndFuncMap(t *testing.T) {
+	fsys := fstest.MapFS{
+		"views/hello.html": {Data: []byte(`{{upper .}}`)},
+	}
+	r := New()
+	r.SetFuncMap(template.FuncMap{"upper": strings.ToUpper})
+	// 两个glob都匹配到同一个文件
+	r.LoadHTMLFS(fsys, "views/*.html", "views/hello.html")
+	r.GET("/", func(c *Context) { c.HTML(http.StatusOK, "hello.html", "gee") })
+
+	if w := performRequest(r, http.MethodGet, "/", nil); w.Body.String() != "GEE" {
+		t.Fatalf("body = %q", w.Body.String())
+	}
+	if r.htmlRender.changed() {
+		t.Fatal("templates reported as changed right after loading")
+	}
+}
+
+func TestHTMLRenderError(t *testing.T) {
+	r := New()
+	r.SetHTMLTemplate(template.Must(template.New("t").Parse(`{{.Missing.Field}}`)))
+	r.GET("/", func(c *Context) { c.HTML(http.StatusOK, "nope", nil) })
+
+	if w := performRequest(r, http.MethodGet, "/", nil); w.Code != http.StatusInternalServerError {
+		t.Fatalf("status = %d, want 500", w.Code)
+	}
+}
@@ -7,8 +7,12 @@ import (
 
 func Logger() Handlerfunc {
 	return func(c *Context) {
+		if Mode() == TestMode {
+			c.Next()
+			return
+		}
 		t := time.Now()
 		c.Next()
-		log.Printf("[%d] %s in %v ", c.StatusCode, c.Req.RequestURI, time.Since(t))
+		log.Printf("[%d] %s %s in %v ", c.Writer.Status(), c.ClientIP(), c.Req.RequestURI, time.Since(t))
 	}
 }
@@ -0,0 +1,443 @@
+package gee
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// DefBuckets 是请求耗时直方图默认的桶边界（单位：秒）。
+var DefBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// DefSizeBuckets 是响应大小直方图默认的桶边界（单位：字节）。
+var DefSizeBuckets = []float64{100, 1000, 10000, 100000, 1e6, 1e7}
+
+// DefaultRegistry 是Metrics中间件和MetricsHandler默认使用的指标注册表。
+var DefaultRegistry = NewRegistry()
+
+// metricNameRe 是Prometheus指标名和标签名允许的格式。
+var metricNameRe = regexp.MustCompile(`^[a-zA-Z_:][a-zA-Z0-9_:]*$`)
+
+// metricKind 是指标的类型，同时也是输出中 # TYPE 行的内容。
+type metricKind string
+
+const (
+	kindCounter   metricKind = "counter"
+	kindGauge     metricKind = "gauge"
+	kindHistogram metricKind = "histogram"
+)
+
+// metricVec 保存一个指标的元数据和按标签值区分的全部序列。
+type metricVec struct {
+	name    string
+	help    string
+	kind    metricKind
+	labels  []string
+	buckets []float64
+	mu      sync.RWMutex
+	series  map[string]*series
+}
+
+// series 是一组标签值对应的一条时间序列。
+type series struct {
+	values []string
+	value  uint64 // counter和gauge的值，以float64的位模式原子存取
+
+	mu     sync.Mutex // 保护直方图的counts、sum和count
+	counts []uint64
+	sum    float64
+	count  uint64
+}
+
+// Registry 保存已注册的指标，并以Prometheus文本格式输出。
+// 它不依赖Prometheus客户端库，只实现了counter、gauge和histogram三种类型。
+type Registry struct {
+	mu      sync.RWMutex
+	metrics map[string]*metricVec
+}
+
+// NewRegistry 创建一个空的指标注册表。
+func NewRegistry() *Registry {
+	return &Registry{metrics: make(map[string]*metricVec)}
+}
+
+// register 注册指标。同名指标已存在且类型和标签都相同时返回已有的指标，
+// 这样多个引擎可以共用同一个注册表；否则panic。
+func (r *Registry) register(kind metricKind, name, help string, buckets []float64, labels []string) *metricVec {
+	if !metricNameRe.MatchString(name) {
+		panic("gee: invalid metric name '" + name + "'")
+	}
+	for _, l := range labels {
+		if !metricNameRe.MatchString(l) || strings.HasPrefix(l, "__") {
+			panic("gee: invalid label name '" + l + "' for metric '" + name + "'")
+		}
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if m, ok := r.metrics[name]; ok {
+		if m.kind != kind || strings.Join(m.labels, ",") != strings.Join(labels, ",") {
+			panic("gee: metric '" + name + "' already registered with a different type or labels")
+		}
+		return m
+	}
+	m := &metricVec{
+		name:    name,
+		help:    help,
+		kind:    kind,
+		labels:  labels,
+		buckets: buckets,
+		series:  make(map[string]*series),
+	}
+	r.metrics[name] = m
+	return m
+}
+
+// NewCounter 注册一个只增不减的计数器。
+func (r *Registry) NewCounter(name, help string, labels ...string) *CounterVec {
+	return &CounterVec{r.register(kindCounter, name, help, nil, labels)}
+}
+
+// NewGauge 注册一个可增可减的仪表盘指标。
+func (r *Registry) NewGauge(name, help string, labels ...string) *GaugeVec {
+	return &GaugeVec{r.register(kindGauge, name, help, nil, labels)}
+}
+
+// NewHistogram 注册一个直方图，buckets为各个桶的上界，为空时使用DefBuckets。
+func (r *Registry) NewHistogram(name, help string, buckets []float64, labels ...string) *HistogramVec {
+	if len(buckets) == 0 {
+		buckets = DefBuckets
+	}
+	b := append([]float64(nil), buckets...)
+	sort.Float64s(b)
+	if math.IsInf(b[len(b)-1], 1) {
+		b = b[:len(b)-1] // +Inf桶总是会被输出
+	}
+	return &HistogramVec{r.register(kindHistogram, name, help, b, labels)}
+}
+
+// with 返回给定标签值对应的序列，不存在时创建。
+func (m *metricVec) with(values []string) *series {
+	if len(values) != len(m.labels) {
+		panic(fmt.Sprintf("gee: metric '%s' expects %d label values, got %d", m.name, len(m.labels), len(values)))
+	}
+	key := strings.Join(values, "\xff")
+	m.mu.RLock()
+	s, ok := m.series[key]
+	m.mu.RUnlock()
+	if ok {
+		return s
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if s, ok = m.series[key]; ok {
+		return s
+	}
+	s = &series{values: append([]string(nil), values...)}
+	if m.kind == kindHistogram {
+		s.counts = make([]uint64, len(m.buckets))
+	}
+	m.series[key] = s
+	return s
+}
+
+// add 原子地给序列的值加上v。
+func (s *series) add(v float64) {
+	for {
+		old := atomic.LoadUint64(&s.value)
+		n := math.Float64bits(math.Float64frombits(old) + v)
+		if atomic.CompareAndSwapUint64(&s.value, old, n) {
+			return
+		}
+	}
+}
+
+func (s *series) load() float64 {
+	return math.Float64frombits(atomic.LoadUint64(&s.value))
+}
+
+// CounterVec 是按标签区分的一组计数器。
+type CounterVec struct{ m *metricVec }
+
+// WithLabelValues 返回给定标签值对应的计数器，标签值的顺序与注册时的标签名一致。
+func (v *CounterVec) WithLabelValues(values ...string) *Counter {
+	return &Counter{v.m.with(values)}
+}
+
+// Counter 是一个只增不减的计数器。
+type Counter struct{ s *series }
+
+// Inc 计数加一。
+func (c *Counter) Inc() { c.s.add(1) }
+
+// Add 计数加上v，v为负数时panic。
+func (c *Counter) Add(v float64) {
+	if v < 0 {
+		panic("gee: counter cannot decrease")
+	}
+	c.s.add(v)
+}
+
+// GaugeVec 是按标签区分的一组仪表盘指标。
+type GaugeVec struct{ m *metricVec }
+
+// WithLabelValues 返回给定标签值对应的仪表盘指标。
+func (v *GaugeVec) WithLabelValues(values ...string) *Gauge {
+	return &Gauge{v.m.with(values)}
+}
+
+// Gauge 是一个可增可减的指标。
+type Gauge struct{ s *series }
+
+// Set 把值设置为v。
+func (g *Gauge) Set(v float64) { atomic.StoreUint64(&g.s.value, math.Float64bits(v)) }
+
+// Inc 值加一。
+func (g *Gauge) Inc() { g.s.add(1) }
+
+// Dec 值减一。
+func (g *Gauge) Dec() { g.s.add(-1) }
+
+// Add 值加上v，v可以为负数。
+func (g *Gauge) Add(v float64) { g.s.add(v) }
+
+// HistogramVec 是按标签区分的一组直方图。
+type HistogramVec struct{ m *metricVec }
+
+// WithLabelValues 返回给定标签值对应的直方图。
+func (v *HistogramVec) WithLabelValues(values ...string) *Histogram {
+	return &Histogram{v.m, v.m.with(values)}
+}
+
+// Histogram 统计观测值落在各个桶中的次数，以及观测值的总和与次数。
+type Histogram struct {
+	m *metricVec
+	s *series
+}
+
+// Observe 记录一个观测值。
+func (h *Histogram) Observe(v float64) {
+	i := sort.SearchFloat64s(h.m.buckets, v) // 第一个上界大于等于v的桶
+	h.s.mu.Lock()
+	if i < len(h.s.counts) {
+		h.s.counts[i]++
+	}
+	h.s.sum += v
+	h.s.count++
+	h.s.mu.Unlock()
+}
+
+// WriteTo 以Prometheus文本格式（text/plain; version=0.0.4）输出全部指标，指标和序列按名称排序。
+func (r *Registry) WriteTo(w io.Writer) (int64, error) {
+	r.mu.RLock()
+	metrics := make([]*metricVec, 0, len(r.metrics))
+	for _, m := range r.metrics {
+		metrics = append(metrics, m)
+	}
+	r.mu.RUnlock()
+	sort.Slice(metrics, func(i, j int) bool { return metrics[i].name < metrics[j].name })
+
+	var buf bytes.Buffer
+	for _, m := range metrics {
+		m.write(&buf)
+	}
+	return buf.WriteTo(w)
+}
+
+// write 输出一个指标的HELP、TYPE行和全部序列。
+func (m *metricVec) write(buf *bytes.Buffer) {
+	m.mu.RLock()
+	all := make([]*series, 0, len(m.series))
+	for _, s := range m.series {
+		all = append(all, s)
+	}
+	m.mu.RUnlock()
+	if len(all) == 0 && len(m.labels) > 0 {
+		return
+	}
+	if len(all) == 0 {
+		all = append(all, m.with(nil)) // 没有标签的指标即使没有记录过也输出0
+	}
+	sort.Slice(all, func(i, j int) bool {
+		return strings.Join(all[i].values, "\xff") < strings.Join(all[j].values, "\xff")
+	})
+
+	fmt.Fprintf(buf, "# HELP %s %s\n", m.name, escapeHelp(m.help))
+	fmt.Fprintf(buf, "# TYPE %s %s\n", m.name, m.kind)
+	for _, s := range all {
+		labels := m.labelPairs(s.values)
+		if m.kind != kindHistogram {
+			fmt.Fprintf(buf, "%s%s %s\n", m.name, formatLabels(labels), formatFloat(s.load()))
+			continue
+		}
+		s.mu.Lock()
+		counts := append([]uint64(nil), s.counts...)
+		sum, count := s.sum, s.count
+		s.mu.Unlock()
+		var cumulative uint64
+		for i, upper := range m.buckets {
+			cumulative += counts[i]
+			le := append(labels, [2]string{"le", formatFloat(upper)})
+			fmt.Fprintf(buf, "%s_bucket%s %d\n", m.name, formatLabels(le), cumulative)
+		}
+		le := append(labels, [2]string{"le", "+Inf"})
+		fmt.Fprintf(buf, "%s_bucket%s %d\n", m.name, formatLabels(le), count)
+		fmt.Fprintf(buf, "%s_sum%s %s\n", m.name, formatLabels(labels), formatFloat(sum))
+		fmt.Fprintf(buf, "%s_count%s %d\n", m.name, formatLabels(labels), count)
+	}
+}
+
+func (m *metricVec) labelPairs(values []string) [][2]string {
+	pairs := make([][2]string, len(values), len(values)+1)
+	for i, v := range values {
+		pairs[i] = [2]string{m.labels[i], v}
+	}
+	return pairs
+}
+
+func formatLabels(pairs [][2]string) string {
+	if len(pairs) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteByte('{')
+	for i, p := range pairs {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(p[0])
+		b.WriteString(`="`)
+		b.WriteString(labelEscaper.Replace(p[1]))
+		b.WriteByte('"')
+	}
+	b.WriteByte('}')
+	return b.String()
+}
+
+var (
+	labelEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+	helpEscaper  = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+)
+
+func escapeHelp(s string) string { return helpEscaper.Replace(s) }
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// MetricsConfig 用于配置Metrics中间件。
+type MetricsConfig struct {
+	// Registry 指标注册到的注册表，为nil时使用DefaultRegistry。
+	Registry *Registry
+	// Namespace 指标名的前缀，例如 "myapp" 得到 myapp_http_requests_total。
+	Namespace string
+	// Buckets 请求耗时直方图的桶边界（秒），为空时使用DefBuckets。
+	Buckets []float64
+	// SizeBuckets 响应大小直方图的桶边界（字节），为空时使用DefSizeBuckets。
+	SizeBuckets []float64
+	// Skip 返回true的请求不记录指标，例如 /metrics 本身。
+	Skip func(c *Context) bool
+}
+
+// unmatchedRoute 是没有匹配到路由的请求使用的route标签值，避免把原始路径当作标签。
+const unmatchedRoute = "<unmatched>"
+
+// otherMethod 是非标准请求方法使用的method标签值，客户端可以发送任意方法名，
+// 直接作为标签值同样会让标签数量无限增长。
+const otherMethod = "OTHER"
+
+// methodLabel 返回请求方法对应的method标签值。
+func methodLabel(method string) string {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch,
+		http.MethodDelete, http.MethodConnect, http.MethodOptions, http.MethodTrace:
+		return method
+	}
+	return otherMethod
+}
+
+// Metrics 返回使用默认配置的指标中间件。
+func Metrics() Handlerfunc {
+	return MetricsWithConfig(MetricsConfig{})
+}
+
+// MetricsWithConfig 按照给定的配置创建指标中间件，记录以下指标：
+//   - http_requests_total{method,route,code}：请求数
+//   - http_request_duration_seconds{method,route}：请求耗时直方图
+//   - http_response_size_bytes{method,route}：响应大小直方图
+//   - http_requests_in_flight：正在处理的请求数
+//
+// route标签是匹配到的路由模式（Context.FullPath），而不是原始路径，避免标签数量无限增长；
+// 同样的原因，非标准的请求方法统一记为 method="OTHER"。
+func MetricsWithConfig(conf MetricsConfig) Handlerfunc {
+	reg := conf.Registry
+	if reg == nil {
+		reg = DefaultRegistry
+	}
+	prefix := ""
+	if conf.Namespace != "" {
+		prefix = conf.Namespace + "_"
+	}
+	sizeBuckets := conf.SizeBuckets
+	if len(sizeBuckets) == 0 {
+		sizeBuckets = DefSizeBuckets
+	}
+	requests := reg.NewCounter(prefix+"http_requests_total", "Total number of HTTP requests.", "method", "route", "code")
+	duration := reg.NewHistogram(prefix+"http_request_duration_seconds", "HTTP request latency in seconds.", conf.Buckets, "method", "route")
+	size := reg.NewHistogram(prefix+"http_response_size_bytes", "HTTP response size in bytes.", sizeBuckets, "method", "route")
+	inFlight := reg.NewGauge(prefix+"http_requests_in_flight", "Number of HTTP requests currently being served.").WithLabelValues()
+
+	return func(c *Context) {
+		if conf.Skip != nil && conf.Skip(c) {
+			c.Next()
+			return
+		}
+		start := time.Now()
+		inFlight.Inc()
+		defer inFlight.Dec()
+
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = unmatchedRoute
+		}
+		written := c.Writer.Size()
+		if written < 0 {
+			written = 0
+		}
+		method := methodLabel(c.Method)
+		requests.WithLabelValues(method, route, strconv.Itoa(c.Writer.Status())).Inc()
+		duration.WithLabelValues(method, route).Observe(time.Since(start).Seconds())
+		size.WithLabelValues(method, route).Observe(float64(written))
+	}
+}
+
+// MetricsHandler 返回以Prometheus文本格式输出reg中全部指标的处理函数，reg为nil时使用DefaultRegistry。
+// 通常注册为 engine.GET("/metrics", gee.MetricsHandler(nil))。
+func MetricsHandler(reg *Registry) Handlerfunc {
+	if reg == nil {
+		reg = DefaultRegistry
+	}
+	return func(c *Context) {
+		c.SetHeader("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		c.Status(http.StatusOK)
+		reg.WriteTo(c.Writer)
+	}
+}
@@ -0,0 +1,107 @@
+package gee
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// scrape 请求/metrics并返回输出的文本。
+func scrape(r *Engine) string {
+	return performRequest(r, http.MethodGet, "/metrics", nil).Body.String()
+}
+
+func assertMetrics(t *testing.T, out string, wants ...string) {
+	t.Helper()
+	for _, want := range wants {
+		if !strings.Contains(out, want) {
+			t.Errorf("missing %q in\n%s", want, out)
+		}
+	}
+}
+
+func TestMetricsMiddleware(t *testing.T) {
+	reg := NewRegistry()
+	r := New()
+	r.Use(MetricsWithConfig(MetricsConfig{
+		Registry: reg,
+		Skip:     func(c *Context) bool { return c.Path == "/metrics" },
+	}))
+	r.GET("/user/:id", func(c *Context) { c.String(http.StatusOK, "hello") })
+	r.Handle("PURGE", "/cache", func(c *Context) { c.Status(http.StatusNoContent) })
+	r.GET("/metrics", MetricsHandler(reg))
+
+	for _, p := range []string{"/user/1", "/user/2", "/nope"} {
+		performRequest(r, http.MethodGet, p, nil)
+	}
+	performRequest(r, "PURGE", "/cache", nil)
+	performRequest(r, "X-RANDOM-1", "/user/1", nil)
+	performRequest(r, "X-RANDOM-2", "/user/1", nil)
+
+	out := scrape(r)
+	assertMetrics(t, out,
+		`http_requests_total{method="GET",route="/user/:id",code="200"} 2`,
+		`http_requests_total{method="GET",route="<unmatched>",code="404"} 1`,
+		`http_requests_total{method="OTHER",route="/cache",code="204"} 1`,
+		`http_requests_total{method="OTHER",route="<unmatched>",code="404"} 2`,
+		`http_response_size_bytes_bucket{method="GET",route="/user/:id",le="100"} 2`,
+		`http_response_size_bytes_sum{method="GET",route="/user/:id"} 10`,
+		`http_request_duration_seconds_count{method="GET",route="/user/:id"} 2`,
+		"# TYPE http_requests_in_flight gauge\nhttp_requests_in_flight 0",
+	)
+	for _, leaked := range []string{"X-RANDOM", "PURGE", `route="/metrics"`, `route="/nope"`} {
+		if strings.Contains(out, leaked) {
+			t.Errorf("%q should not appear in\n%s", leaked, out)
+		}
+	}
+}
+
+func TestMetricsSharedRegistry(t *testing.T) {
+	reg := NewRegistry()
+	a, b := New(), New()
+	for _, r := range []*Engine{a, b} {
+		r.Use(MetricsWithConfig(MetricsConfig{Registry: reg, Namespace: "app"}))
+		r.GET("/", func(c *Context) { c.String(http.StatusOK, "ok") })
+	}
+	performRequest(a, http.MethodGet, "/", nil)
+	performRequest(b, http.MethodGet, "/", nil)
+
+	r := New()
+	r.GET("/metrics", MetricsHandler(reg))
+	assertMetrics(t, scrape(r), `app_http_requests_total{method="GET",route="/",code="200"} 2`)
+}
+
+func TestRegistry(t *testing.T) {
+	reg := NewRegistry()
+	jobs := reg.NewCounter("jobs_total", "Jobs by \"queue\".\nSecond line.", "queue")
+	jobs.WithLabelValues("a\"b\\c\nd").Add(2)
+	temp := reg.NewGauge("temperature", "Current temperature.")
+	temp.WithLabelValues().Set(21.5)
+	temp.WithLabelValues().Dec()
+	hist := reg.NewHistogram("latency", "Latency.", []float64{1, 0.1}, "op")
+	hist.WithLabelValues("read").Observe(0.05)
+	hist.WithLabelValues("read").Observe(5)
+
+	var buf strings.Builder
+	if _, err := reg.WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+	assertMetrics(t, buf.String(),
+		"# HELP jobs_total Jobs by \"queue\".\\nSecond line.\n# TYPE jobs_total counter",
+		`jobs_total{queue="a\"b\\c\nd"} 2`,
+		"temperature 20.5",
+		`latency_bucket{op="read",le="0.1"} 1`,
+		`latency_bucket{op="read",le="1"} 1`,
+		`latency_bucket{op="read",le="+Inf"} 2`,
+		`latency_count{op="read"} 2`,
+	)
+
+	if again := reg.NewCounter("jobs_total", "other help", "queue"); again.m != jobs.m {
+		t.Error("registering the same counter twice did not return the existing metric")
+	}
+	mustPanic(t, "already registered", func() { reg.NewGauge("jobs_total", "", "queue") })
+	mustPanic(t, "invalid metric name", func() { reg.NewCounter("1bad", "") })
+	mustPanic(t, "invalid label name", func() { reg.NewCounter("ok_total", "", "__reserved") })
+	mustPanic(t, "expects 1 label values", func() { jobs.WithLabelValues() })
+	mustPanic(t, "cannot decrease", func() { jobs.WithLabelValues("a").Add(-1) })
+}
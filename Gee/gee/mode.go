@@ -0,0 +1,91 @@
+package gee
+
+import (
+	"log"
+	"net/http"
+	"os"
+	"sync/atomic"
+)
+
+// 引擎的运行模式
+const (
+	// DebugMode 输出路由注册等调试日志，开启模板热加载，并对常见的配置问题给出警告，
+	// 例如没有中间件恢复处理函数中的panic。
+	DebugMode = "debug"
+	// ReleaseMode 只输出服务器启动、关闭和请求日志。
+	ReleaseMode = "release"
+	// TestMode 不输出框架日志和默认的请求日志，适合单元测试。
+	TestMode = "test"
+)
+
+// EnvGeeMode 是设置运行模式的环境变量名。
+const EnvGeeMode = "GEE_MODE"
+
+// geeMode 保存当前的运行模式。
+var geeMode atomic.Value
+
+func init() {
+	SetMode(os.Getenv(EnvGeeMode))
+}
+
+// SetMode 设置运行模式，value为空时使用DebugMode，未知的模式会导致panic。
+// 默认模式由环境变量GEE_MODE决定。模式影响之后创建的引擎，应在调用New之前设置。
+func SetMode(value string) {
+	switch value {
+	case "":
+		value = DebugMode
+	case DebugMode, ReleaseMode, TestMode:
+	default:
+		panic("gee: unknown mode '" + value + "', available modes: debug, release, test")
+	}
+	geeMode.Store(value)
+}
+
+// Mode 返回当前的运行模式。
+func Mode() string {
+	return geeMode.Load().(string)
+}
+
+// IsDebugging 报告当前是否为DebugMode。
+func IsDebugging() bool {
+	return Mode() == DebugMode
+}
+
+// debugPrintf 只在DebugMode下输出日志。
+func debugPrintf(format string, values ...interface{}) {
+	if IsDebugging() {
+		log.Printf("[GEE-debug] "+format, values...)
+	}
+}
+
+// infoPrintf 输出服务器启动、关闭等信息，TestMode下不输出。
+func infoPrintf(format string, values ...interface{}) {
+	if Mode() != TestMode {
+		log.Printf(format, values...)
+	}
+}
+
+// debugWarnings 在DebugMode下启动服务器前提示仍在使用调试模式。
+func (engine *Engine) debugWarnings() {
+	if !IsDebugging() {
+		return
+	}
+	debugPrintf("[WARNING] Running in \"debug\" mode. Switch to \"release\" mode in production.\n" +
+		" - using env:\texport GEE_MODE=release\n" +
+		" - using code:\tgee.SetMode(gee.ReleaseMode)")
+}
+
+// warnUnrecovered 在DebugMode下由ServeHTTP延迟调用：panic越过了整个处理链，
+// 说明没有使用Recovery或其他恢复panic的中间件，第一次发生时给出警告，然后继续向上panic。
+// 按行为判断而不是识别具体的中间件，因此自定义或包装过的恢复中间件同样有效。
+func (engine *Engine) warnUnrecovered() {
+	err := recover()
+	if err == nil {
+		return
+	}
+	if err != http.ErrAbortHandler && !engine.panicWarned.Swap(true) {
+		debugPrintf("[WARNING] A panic was not recovered by any middleware, the connection is closed without a response.\n" +
+			" - using code:\tr.Use(gee.Recovery())")
+	}
+	panic(err)
+}
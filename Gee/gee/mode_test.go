@@ -0,0 +1,110 @@
+package gee
+
+import (
+	"bytes"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+)
+
+// captureLog 在fn执行期间收集log包的默认输出。
+func captureLog(fn func()) string {
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	defer log.SetOutput(os.Stderr)
+	fn()
+	return buf.String()
+}
+
+func TestSetMode(t *testing.T) {
+	defer SetMode(Mode())
+	SetMode("")
+	if Mode() != DebugMode || !IsDebugging() {
+		t.Fatalf("empty mode = %q, want debug", Mode())
+	}
+	SetMode(ReleaseMode)
+	if Mode() != ReleaseMode || IsDebugging() {
+		t.Fatalf("mode = %q, want release", Mode())
+	}
+	defer func() {
+		if recover() == nil {
+			t.Fatal("unknown mode did not panic")
+		}
+	}()
+	SetMode("prod")
+}
+
+func TestModeTemplateReload(t *testing.T) {
+	withMode(DebugMode, func() {
+		if !New().TemplateReload {
+			t.Fatal("debug mode should enable TemplateReload")
+		}
+	})
+	withMode(ReleaseMode, func() {
+		if New().TemplateReload {
+			t.Fatal("release mode should disable TemplateReload")
+		}
+	})
+}
+
+func TestDebugModeLogsRoutes(t *testing.T) {
+	out := captureLog(func() {
+		withMode(DebugMode, func() { New().GET("/x", func(c *Context) {}) })
+		withMode(ReleaseMode, func() { New().GET("/y", func(c *Context) {}) })
+	})
+	if !strings.Contains(out, "GET - /x") || strings.Contains(out, "/y") {
+		t.Fatalf("log = %q", out)
+	}
+}
+
+func TestTestModeIsSilent(t *testing.T) {
+	out := captureLog(func() {
+		r := New()
+		r.Use(Logger(), Recovery())
+		r.GET("/panic", func(c *Context) { panic("x") })
+		if w := performRequest(r, http.MethodGet, "/panic", nil); w.Code != http.StatusInternalServerError {
+			t.Fatalf("status = %d", w.Code)
+		}
+	})
+	if out != "" {
+		t.Fatalf("test mode logged %q", out)
+	}
+}
+
+func TestDebugModeWarnsUnrecoveredPanic(t *testing.T) {
+	panics := func(r *Engine) (recovered interface{}) {
+		defer func() { recovered = recover() }()
+		performRequest(r, http.MethodGet, "/panic", nil)
+		return nil
+	}
+	withMode(DebugMode, func() {
+		r := New()
+		r.GET("/panic", func(c *Context) { panic("x") })
+		out := captureLog(func() {
+			if panics(r) != "x" || panics(r) != "x" {
+				t.Fatal("panic was swallowed")
+			}
+		})
+		if strings.Count(out, "not recovered") != 1 {
+			t.Fatalf("log = %q, want one warning", out)
+		}
+
+		// 用户自己编写的恢复中间件同样有效
+		r = New()
+		r.Use(func(c *Context) {
+			defer func() {
+				if recover() != nil {
+					c.Status(http.StatusInternalServerError)
+				}
+			}()
+			c.Next()
+		})
+		r.GET("/panic", func(c *Context) { panic("x") })
+		out = captureLog(func() { panics(r) })
+		if strings.Contains(out, "not recovered") {
+			t.Fatalf("warned although the panic was recovered: %q", out)
+		}
+	})
+}
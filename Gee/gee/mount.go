@@ -0,0 +1,123 @@
+package gee
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// mountParam 是Mount注册的通配路由中，保存剩余路径的参数名。
+const mountParam = "gee_mount_path"
+
+// paramsKey 是挂载时在请求的context中保存父路由参数的键。
+type paramsKey struct{}
+
+// WrapF 把http.HandlerFunc转换为Handlerfunc。
+func WrapF(f http.HandlerFunc) Handlerfunc {
+	return func(c *Context) {
+		f(c.Writer, c.Req)
+	}
+}
+
+// WrapH 把http.Handler转换为Handlerfunc，例如pprof或LGRPC的调试页面。
+func WrapH(h http.Handler) Handlerfunc {
+	return func(c *Context) {
+		h.ServeHTTP(c.Writer, c.Req)
+	}
+}
+
+// RequestParams 返回挂载点上匹配到的路由参数，供挂载的普通http.Handler使用。
+// 挂载的gee.Engine会自动把这些参数合并到Context.Params中。
+func RequestParams(req *http.Request) map[string]string {
+	params, _ := req.Context().Value(paramsKey{}).(map[string]string)
+	return params
+}
+
+// mountedParams 复制父路由的参数，作为子引擎Context的初始参数。
+func mountedParams(req *http.Request) map[string]string {
+	parent := RequestParams(req)
+	if len(parent) == 0 {
+		return nil
+	}
+	params := make(map[string]string, len(parent))
+	for k, v := range parent {
+		params[k] = v
+	}
+	return params
+}
+
+// Mount 把http.Handler（包括另一个gee.Engine）挂载到分组的prefix下，所有请求方法都会被转发，
+// 包括PROPFIND、MKCOL等自定义方法。
+// 转发前会去掉请求路径中的挂载前缀，分组的中间件照常执行，
+// 前缀中的路由参数（例如 /tenants/:id/legacy）通过请求的context传递给被挂载的处理器。
+// 挂载点不会出现在Routes和OpenAPI文档中。
+func (group *RouteGroup) Mount(prefix string, handler http.Handler) {
+	prefix = "/" + strings.Trim(prefix, "/")
+	if strings.Contains(prefix, "*") {
+		panic("gee: wildcard is not allowed in mount prefix")
+	}
+	// 挂载点在完整路径中占用的路径段数
+	segments := len(parsePattern(group.prefix + prefix))
+	mounted := func(c *Context) {
+		params := make(map[string]string, len(c.Params))
+		for k, v := range RequestParams(c.Req) {
+			params[k] = v
+		}
+		for k, v := range c.Params {
+			if k != mountParam {
+				params[k] = v
+			}
+		}
+		req := c.Req.WithContext(context.WithValue(c.Req.Context(), paramsKey{}, params))
+		req.URL = new(url.URL)
+		*req.URL = *c.Req.URL
+		req.URL.Path = stripSegments(c.Req.URL.Path, segments)
+		req.URL.RawPath = stripRawPath(c.Req.URL.RawPath, req.URL.Path)
+		handler.ServeHTTP(c.Writer, req)
+	}
+	for _, pattern := range []string{prefix, strings.TrimSuffix(prefix, "/") + "/*" + mountParam} {
+		group.mountRoute(pattern, mounted)
+	}
+}
+
+// mountRoute 注册挂载点的内部路由。标准方法与其他路由一起注册，保持原有的匹配优先级；
+// 其他方法通过不区分方法的路由转发。内部路由不记录到Routes中，因此也不会出现在OpenAPI文档里。
+func (group *RouteGroup) mountRoute(comp string, handler Handlerfunc) {
+	pattern := group.prefix + comp
+	for _, method := range append(anyMethods[:len(anyMethods):len(anyMethods)], anyMethod) {
+		debugPrintf("Route %4s - %s", method, pattern)
+		group.engine.router.addRoute(method, pattern, handler)
+	}
+}
+
+// stripRawPath 在编码后的路径raw中找出解码后等于path的后缀。
+// 编码的"/"（%2F）会使raw和path的路径段数不同，因此不能按路径段数截取；找不到时返回空字符串，由URL根据path重新编码。
+func stripRawPath(raw, path string) string {
+	for i := 0; i < len(raw); i++ {
+		if raw[i] != '/' {
+			continue
+		}
+		if p, err := url.PathUnescape(raw[i:]); err == nil && p == path {
+			return raw[i:]
+		}
+	}
+	return ""
+}
+
+// stripSegments 去掉路径开头的n个非空路径段，保留结尾的斜杠，结果总是以"/"开头。
+func stripSegments(p string, n int) string {
+	rest := p
+	for i := 0; i < n; i++ {
+		rest = strings.TrimLeft(rest, "/")
+		if idx := strings.IndexByte(rest, '/'); idx >= 0 {
+			rest = rest[idx:]
+		} else {
+			rest = ""
+		}
+	}
+	if !strings.HasPrefix(rest, "/") {
+		rest = "/" + rest
+	}
+	return rest
+}
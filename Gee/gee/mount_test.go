@@ -0,0 +1,118 @@
+package gee
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+func TestMountEngine(t *testing.T) {
+	sub := New()
+	sub.GET("/", func(c *Context) { c.String(http.StatusOK, "root %s", c.Param("tenant")) })
+	sub.GET("/users/:id", func(c *Context) {
+		c.String(http.StatusOK, "user %s %s %s", c.Param("id"), c.Param("tenant"), c.Path)
+	})
+	r := New()
+	g := r.Group("/t/:tenant")
+	g.Use(func(c *Context) { c.SetHeader("X-Group", "1"); c.Next() })
+	g.Mount("/app", sub)
+
+	tests := []struct {
+		path string
+		code int
+		body string
+	}{
+		{"/t/acme/app", http.StatusOK, "root acme"},
+		{"/t/acme/app/", http.StatusOK, "root acme"},
+		{"/t/acme/app/users/7", http.StatusOK, "user 7 acme /users/7"},
+		{"/t/acme/app/nope", http.StatusNotFound, "404 NOT FOUND: /nope\n"},
+	}
+	for _, tt := range tests {
+		w := performRequest(r, http.MethodGet, tt.path, nil)
+		if w.Code != tt.code || w.Body.String() != tt.body {
+			t.Errorf("GET %s = %d %q, want %d %q", tt.path, w.Code, w.Body.String(), tt.code, tt.body)
+		}
+		if w.Header().Get("X-Group") != "1" {
+			t.Errorf("GET %s: group middleware did not run", tt.path)
+		}
+	}
+}
+
+func TestMountHandler(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/hello/", func(w http.ResponseWriter, req *http.Request) {
+		fmt.Fprintf(w, "%s %s %v", req.Method, req.URL.Path, RequestParams(req)["tenant"])
+	})
+	r := New()
+	r.Group("/t/:tenant").Mount("/legacy", mux)
+
+	w := performRequest(r, http.MethodPost, "/t/acme/legacy/hello/x", nil)
+	if w.Body.String() != "POST /hello/x acme" {
+		t.Fatalf("body = %q", w.Body.String())
+	}
+	for _, method := range []string{"PROPFIND", "MKCOL"} {
+		if w := performRequest(r, method, "/t/acme/legacy/hello/x", nil); w.Body.String() != method+" /hello/x acme" {
+			t.Errorf("%s = %d %q", method, w.Code, w.Body.String())
+		}
+	}
+	if w := performRequest(r, "PROPFIND", "/t/acme/other", nil); w.Code != http.StatusNotFound {
+		t.Errorf("PROPFIND outside the mount = %d, want 404", w.Code)
+	}
+	defer func() {
+		if recover() == nil {
+			t.Fatal("wildcard mount prefix did not panic")
+		}
+	}()
+	r.Mount("/files/*path", mux)
+}
+
+func TestMountEscapedPath(t *testing.T) {
+	r := New()
+	r.Mount("/legacy/v1", http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		fmt.Fprintf(w, "%s %s", req.URL.Path, req.URL.EscapedPath())
+	}))
+	tests := []struct {
+		path, want string
+	}{
+		{"/legacy/v1/a%2Fb", "/a/b /a%2Fb"},
+		{"/legacy%2Fv1/a%2Fb", "/a/b /a%2Fb"},
+		{"/legacy%2Fv1/c", "/c /c"},
+		{"/legacy/v1/plain", "/plain /plain"},
+	}
+	for _, tt := range tests {
+		if w := performRequest(r, http.MethodGet, tt.path, nil); w.Body.String() != tt.want {
+			t.Errorf("GET %s = %q, want %q", tt.path, w.Body.String(), tt.want)
+		}
+	}
+}
+
+func TestWrap(t *testing.T) {
+	r := New()
+	r.GET("/f", WrapF(func(w http.ResponseWriter, req *http.Request) { w.Write([]byte("wrapf")) }))
+	r.GET("/h", WrapH(http.NotFoundHandler()))
+
+	if w := performRequest(r, http.MethodGet, "/f", nil); w.Body.String() != "wrapf" {
+		t.Fatalf("WrapF body = %q", w.Body.String())
+	}
+	if w := performRequest(r, http.MethodGet, "/h", nil); w.Code != http.StatusNotFound {
+		t.Fatalf("WrapH status = %d", w.Code)
+	}
+}
+
+func TestStripSegments(t *testing.T) {
+	tests := []struct {
+		path string
+		n    int
+		want string
+	}{
+		{"/a/b/c", 1, "/b/c"},
+		{"/a/b/", 2, "/"},
+		{"/a", 1, "/"},
+		{"/a/b/c/", 1, "/b/c/"},
+	}
+	for _, tt := range tests {
+		if got := stripSegments(tt.path, tt.n); got != tt.want {
+			t.Errorf("stripSegments(%q, %d) = %q, want %q", tt.path, tt.n, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,525 @@
+package gee
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RouteInfo 描述一条已注册的路由。
+type RouteInfo struct {
+	Method string // 请求方法
+	Path   string // 完整的路由模式，例如 /user/:id
+}
+
+// Routes 按注册顺序返回引擎中的全部路由。
+func (engine *Engine) Routes() []RouteInfo {
+	return append([]RouteInfo(nil), engine.routes...)
+}
+
+// Operation 是一条路由在OpenAPI文档中的注解，全部字段都是可选的。
+// Query、Request和Responses中的值只用于反射类型，传入零值即可，例如 User{} 或 []User{}。
+type Operation struct {
+	Summary     string
+	Description string
+	Tags        []string
+	OperationID string
+	Deprecated  bool
+	Query       interface{}         // 查询参数结构体，参数名取form标签，其次是json标签
+	Request     interface{}         // JSON请求体
+	Responses   map[int]interface{} // 状态码对应的JSON响应体，值为nil表示没有响应体
+}
+
+// Doc 为分组内的路由添加OpenAPI注解，method和pattern与注册路由时相同，pattern相对于分组前缀。
+func (group *RouteGroup) Doc(method, pattern string, op Operation) {
+	engine := group.engine
+	if engine.docs == nil {
+		engine.docs = make(map[string]Operation)
+	}
+	engine.docs[method+"-"+group.prefix+pattern] = op
+}
+
+// OpenAPIConfig 用于配置生成的OpenAPI文档。
+type OpenAPIConfig struct {
+	Title       string   // 文档标题，默认为 "API"
+	Version     string   // API版本，默认为 "1.0.0"
+	Description string   // 文档描述
+	Servers     []string // 服务器地址，例如 https://api.example.com
+	// Include 返回false的路由不会出现在文档中，为nil时包含全部路由。
+	Include func(route RouteInfo) bool
+}
+
+// openAPIMethods 是OpenAPI路径项支持的请求方法，CONNECT不在其中。
+var openAPIMethods = map[string]int{
+	http.MethodGet: 0, http.MethodPut: 1, http.MethodPost: 2, http.MethodDelete: 3,
+	http.MethodOptions: 4, http.MethodHead: 5, http.MethodPatch: 6, http.MethodTrace: 7,
+}
+
+// OpenAPI 在path注册一个GET端点，返回由已注册路由生成的OpenAPI 3 JSON文档。
+// 文档在每次请求时生成，因此之后注册的路由同样会出现在文档中。
+func (engine *Engine) OpenAPI(path string, conf OpenAPIConfig) {
+	include := conf.Include
+	conf.Include = func(route RouteInfo) bool {
+		if route.Path == path {
+			return false
+		}
+		return include == nil || include(route)
+	}
+	engine.GET(path, func(c *Context) {
+		c.JSON(http.StatusOK, engine.OpenAPISpec(conf))
+	})
+}
+
+// OpenAPISpec 由已注册的路由和注解生成OpenAPI 3文档，返回值可以直接编码为JSON，也可以修改后再输出。
+func (engine *Engine) OpenAPISpec(conf OpenAPIConfig) map[string]interface{} {
+	if conf.Title == "" {
+		conf.Title = "API"
+	}
+	if conf.Version == "" {
+		conf.Version = "1.0.0"
+	}
+	info := map[string]interface{}{"title": conf.Title, "version": conf.Version}
+	if conf.Description != "" {
+		info["description"] = conf.Description
+	}
+	doc := map[string]interface{}{"openapi": "3.0.3", "info": info}
+	if len(conf.Servers) > 0 {
+		servers := make([]interface{}, len(conf.Servers))
+		for i, url := range conf.Servers {
+			servers[i] = map[string]interface{}{"url": url}
+		}
+		doc["servers"] = servers
+	}
+
+	routes := engine.Routes()
+	sort.SliceStable(routes, func(i, j int) bool {
+		if routes[i].Path != routes[j].Path {
+			return routes[i].Path < routes[j].Path
+		}
+		return openAPIMethods[routes[i].Method] < openAPIMethods[routes[j].Method]
+	})
+	gen := &schemaGen{components: make(map[string]interface{}), names: make(map[reflect.Type]string)}
+	paths := make(map[string]interface{})
+	for _, route := range routes {
+		if _, ok := openAPIMethods[route.Method]; !ok {
+			continue
+		}
+		if conf.Include != nil && !conf.Include(route) {
+			continue
+		}
+		path, params := openAPIPath(route.Path)
+		item, _ := paths[path].(map[string]interface{})
+		if item == nil {
+			item = make(map[string]interface{})
+			paths[path] = item
+		}
+		item[strings.ToLower(route.Method)] = gen.operation(params, engine.docs[route.Method+"-"+route.Path])
+	}
+	doc["paths"] = paths
+	if len(gen.components) > 0 {
+		doc["components"] = map[string]interface{}{"schemas": gen.components}
+	}
+	return doc
+}
+
+// pathParam 是从路由模式中解析出的路径参数。
+type pathParam struct {
+	name       string
+	constraint string
+	catchAll   bool
+}
+
+// openAPIPath 把路由模式转换为OpenAPI的路径模板，例如 /files/:name.:ext 转换为 /files/{name}.{ext}。
+func openAPIPath(pattern string) (string, []pathParam) {
+	var params []pathParam
+	parts := parsePattern(pattern)
+	for i, part := range parts {
+		switch {
+		case part[0] == '*':
+			name := part[1:]
+			if name == "" {
+				name = "path"
+			}
+			params = append(params, pathParam{name: name, catchAll: true})
+			parts[i] = "{" + name + "}"
+		case isCompositeSegment(part):
+			var b strings.Builder
+			for j := 0; j < len(part); {
+				if part[j] != ':' {
+					b.WriteByte(part[j])
+					j++
+					continue
+				}
+				k := j + 1
+				for k < len(part) && isParamNameChar(part[k]) {
+					k++
+				}
+				p := pathParam{name: part[j+1 : k]}
+				if k < len(part) && part[k] == '<' {
+					if end := strings.IndexByte(part[k:], '>'); end >= 0 {
+						p.constraint = part[k+1 : k+end]
+						k += end + 1
+					}
+				}
+				params = append(params, p)
+				b.WriteString("{" + p.name + "}")
+				j = k
+			}
+			parts[i] = b.String()
+		case part[0] == ':':
+			name, constraint := splitParam(part)
+			params = append(params, pathParam{name: name, constraint: constraint})
+			parts[i] = "{" + name + "}"
+		}
+	}
+	return "/" + strings.Join(parts, "/"), params
+}
+
+// schema 返回路径参数的模式，内置约束转换为对应的类型或格式。
+func (p pathParam) schema() map[string]interface{} {
+	switch p.constraint {
+	case "":
+		return map[string]interface{}{"type": "string"}
+	case "int":
+		return map[string]interface{}{"type": "integer"}
+	case "uint":
+		return map[string]interface{}{"type": "integer", "minimum": 0}
+	case "uuid":
+		return map[string]interface{}{"type": "string", "format": "uuid"}
+	}
+	expr := p.constraint
+	if builtin, ok := paramConstraints[expr]; ok {
+		expr = builtin
+	}
+	return map[string]interface{}{"type": "string", "pattern": "^(?:" + expr + ")$"}
+}
+
+// schemaGen 通过反射生成JSON Schema，具名结构体放入components并以$ref引用。
+type schemaGen struct {
+	components map[string]interface{}
+	names      map[reflect.Type]string
+}
+
+// operation 生成一个操作对象。
+func (g *schemaGen) operation(params []pathParam, op Operation) map[string]interface{} {
+	out := make(map[string]interface{})
+	if op.Summary != "" {
+		out["summary"] = op.Summary
+	}
+	if op.Description != "" {
+		out["description"] = op.Description
+	}
+	if len(op.Tags) > 0 {
+		out["tags"] = op.Tags
+	}
+	if op.OperationID != "" {
+		out["operationId"] = op.OperationID
+	}
+	if op.Deprecated {
+		out["deprecated"] = true
+	}
+
+	var parameters []interface{}
+	for _, p := range params {
+		param := map[string]interface{}{"name": p.name, "in": "path", "required": true, "schema": p.schema()}
+		if p.catchAll {
+			param["description"] = "Matches the rest of the path."
+		}
+		parameters = append(parameters, param)
+	}
+	if op.Query != nil {
+		parameters = append(parameters, g.queryParams(reflect.TypeOf(op.Query))...)
+	}
+	if len(parameters) > 0 {
+		out["parameters"] = parameters
+	}
+
+	if op.Request != nil {
+		out["requestBody"] = map[string]interface{}{
+			"required": true,
+			"content":  jsonContent(g.schema(reflect.TypeOf(op.Request))),
+		}
+	}
+	responses := make(map[string]interface{})
+	for code, body := range op.Responses {
+		resp := map[string]interface{}{"description": http.StatusText(code)}
+		if body != nil {
+			resp["content"] = jsonContent(g.schema(reflect.TypeOf(body)))
+		}
+		responses[strconv.Itoa(code)] = resp
+	}
+	if len(responses) == 0 {
+		responses["200"] = map[string]interface{}{"description": http.StatusText(http.StatusOK)}
+	}
+	out["responses"] = responses
+	return out
+}
+
+func jsonContent(schema map[string]interface{}) map[string]interface{} {
+	return map[string]interface{}{"application/json": map[string]interface{}{"schema": schema}}
+}
+
+// queryParams 把结构体的字段转换为查询参数。
+func (g *schemaGen) queryParams(t reflect.Type) []interface{} {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return nil
+	}
+	var params []interface{}
+	for _, f := range structFields(t, "form") {
+		schema := g.schema(f.field.Type)
+		required := applyValidation(schema, f.field)
+		params = append(params, map[string]interface{}{"name": f.name, "in": "query", "required": required, "schema": schema})
+	}
+	return params
+}
+
+// namedField 是结构体中一个会被编码的字段。
+type namedField struct {
+	name  string
+	field reflect.StructField
+}
+
+// structFields 按encoding/json的规则列出字段：跳过未导出字段和 "-"，展开没有名称的匿名结构体。
+// tag指定优先使用的标签，其次是json标签，都没有时使用字段名。
+func structFields(t reflect.Type, tag string) []namedField {
+	var fields []namedField
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name := ""
+		for _, key := range []string{tag, "json"} {
+			if v, ok := f.Tag.Lookup(key); ok {
+				name = strings.Split(v, ",")[0]
+				break
+			}
+		}
+		if name == "-" {
+			continue
+		}
+		ft := f.Type
+		for ft.Kind() == reflect.Ptr {
+			ft = ft.Elem()
+		}
+		if f.Anonymous && name == "" && ft.Kind() == reflect.Struct {
+			fields = append(fields, structFields(ft, tag)...)
+			continue
+		}
+		if !f.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		fields = append(fields, namedField{name: name, field: f})
+	}
+	return fields
+}
+
+var (
+	timeType  = reflect.TypeOf(time.Time{})
+	bytesType = reflect.TypeOf([]byte(nil))
+)
+
+// schema 返回类型t的JSON Schema。
+func (g *schemaGen) schema(t reflect.Type) map[string]interface{} {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch t {
+	case timeType:
+		return map[string]interface{}{"type": "string", "format": "date-time"}
+	case bytesType:
+		return map[string]interface{}{"type": "string", "format": "byte"}
+	}
+	switch t.Kind() {
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Int32:
+		return map[string]interface{}{"type": "integer", "format": "int32"}
+	case reflect.Int64:
+		return map[string]interface{}{"type": "integer", "format": "int64"}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return map[string]interface{}{"type": "integer", "minimum": 0}
+	case reflect.Float32:
+		return map[string]interface{}{"type": "number", "format": "float"}
+	case reflect.Float64:
+		return map[string]interface{}{"type": "number", "format": "double"}
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Slice, reflect.Array:
+		return map[string]interface{}{"type": "array", "items": g.schema(t.Elem())}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": g.schema(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return g.object(t)
+		}
+		name, ok := g.names[t]
+		if !ok {
+			name = g.componentName(t)
+			g.names[t] = name
+			g.components[name] = map[string]interface{}{} // 先占位，支持递归引用
+			g.components[name] = g.object(t)
+		}
+		return map[string]interface{}{"$ref": "#/components/schemas/" + name}
+	}
+	return map[string]interface{}{} // interface{}等任意类型
+}
+
+// componentName 返回结构体在components中的名称，重名时加上包名。
+func (g *schemaGen) componentName(t reflect.Type) string {
+	clean := func(s string) string {
+		return strings.Map(func(r rune) rune {
+			if r == '_' || r == '.' || r == '-' || ('a' <= r && r <= 'z') || ('A' <= r && r <= 'Z') || ('0' <= r && r <= '9') {
+				return r
+			}
+			return '_'
+		}, s)
+	}
+	name := clean(t.Name())
+	if _, taken := g.components[name]; !taken {
+		return name
+	}
+	pkg := t.PkgPath()
+	name = clean(pkg[strings.LastIndexByte(pkg, '/')+1:] + "." + t.Name())
+	for i := 2; ; i++ {
+		if _, taken := g.components[name]; !taken {
+			return name
+		}
+		name = clean(t.Name()) + strconv.Itoa(i)
+	}
+}
+
+// object 生成结构体的对象模式，required来自验证标签。
+func (g *schemaGen) object(t reflect.Type) map[string]interface{} {
+	props := make(map[string]interface{})
+	var required []string
+	for _, f := range structFields(t, "json") {
+		schema := g.schema(f.field.Type)
+		if applyValidation(schema, f.field) {
+			required = append(required, f.name)
+		}
+		if desc := f.field.Tag.Get("description"); desc != "" {
+			schema = withDescription(schema, desc)
+		}
+		props[f.name] = schema
+	}
+	obj := map[string]interface{}{"type": "object", "properties": props}
+	if len(required) > 0 {
+		obj["required"] = required
+	}
+	return obj
+}
+
+// withDescription 给模式添加描述。$ref不能与其他字段并列，因此用allOf包装。
+func withDescription(schema map[string]interface{}, desc string) map[string]interface{} {
+	if _, ok := schema["$ref"]; ok {
+		return map[string]interface{}{"allOf": []interface{}{schema}, "description": desc}
+	}
+	schema["description"] = desc
+	return schema
+}
+
+// applyValidation 把validate或binding标签中的规则转换为模式约束，返回字段是否必填。
+// 支持 required、min、max、len、gt、gte、lt、lte、oneof、email、url、uri、uuid、ipv4、ipv6、datetime，
+// 遇到dive时停止，之后的规则作用于元素。
+func applyValidation(schema map[string]interface{}, f reflect.StructField) bool {
+	tag, ok := f.Tag.Lookup("validate")
+	if !ok {
+		tag = f.Tag.Get("binding")
+	}
+	if tag == "" {
+		return false
+	}
+	t := f.Type
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if _, isRef := schema["$ref"]; isRef {
+		return strings.Contains(","+tag+",", ",required,")
+	}
+	var minKey, maxKey string
+	switch t.Kind() {
+	case reflect.String:
+		minKey, maxKey = "minLength", "maxLength"
+	case reflect.Slice, reflect.Array:
+		minKey, maxKey = "minItems", "maxItems"
+	case reflect.Map:
+		minKey, maxKey = "minProperties", "maxProperties"
+	default:
+		minKey, maxKey = "minimum", "maximum"
+	}
+	numeric := minKey == "minimum"
+	parse := func(v string) interface{} {
+		if n, err := strconv.ParseFloat(v, 64); err == nil {
+			if n == float64(int64(n)) {
+				return int64(n)
+			}
+			return n
+		}
+		return v
+	}
+
+	required := false
+	for _, rule := range strings.Split(tag, ",") {
+		key, value, _ := strings.Cut(rule, "=")
+		switch key {
+		case "dive":
+			return required
+		case "required":
+			required = true
+		case "min":
+			schema[minKey] = parse(value)
+		case "max":
+			schema[maxKey] = parse(value)
+		case "len":
+			schema[minKey], schema[maxKey] = parse(value), parse(value)
+		case "gte":
+			schema[minKey] = parse(value)
+		case "lte":
+			schema[maxKey] = parse(value)
+		case "gt":
+			if numeric {
+				schema["minimum"], schema["exclusiveMinimum"] = parse(value), true
+			}
+		case "lt":
+			if numeric {
+				schema["maximum"], schema["exclusiveMaximum"] = parse(value), true
+			}
+		case "oneof":
+			var enum []interface{}
+			for _, v := range strings.Fields(value) {
+				if numeric {
+					enum = append(enum, parse(v))
+				} else {
+					enum = append(enum, v)
+				}
+			}
+			schema["enum"] = enum
+		case "email":
+			schema["format"] = "email"
+		case "url", "uri":
+			schema["format"] = "uri"
+		case "uuid", "uuid4":
+			schema["format"] = "uuid"
+		case "ipv4", "ipv6":
+			schema["format"] = key
+		case "datetime":
+			schema["format"] = "date-time"
+		}
+	}
+	return required
+}
+
+// MarshalOpenAPI 生成OpenAPI文档并编码为带缩进的JSON，便于写入文件。
+func (engine *Engine) MarshalOpenAPI(conf OpenAPIConfig) ([]byte, error) {
+	return json.MarshalIndent(engine.OpenAPISpec(conf), "", "  ")
+}
@@ -0,0 +1,212 @@
+package gee
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type docBase struct {
+	ID int64 `json:"id"`
+}
+
+type docUser struct {
+	docBase
+	Name    string     `json:"name" validate:"required,min=2,max=20" description:"Display name"`
+	Email   string     `json:"email,omitempty" validate:"omitempty,email"`
+	Role    string     `json:"role" binding:"oneof=admin user"`
+	Age     int        `json:"age" validate:"gte=0,lt=150"`
+	Friends []*docUser `json:"friends"`
+	Created time.Time  `json:"created"`
+	Ignored string     `json:"-"`
+	secret  string
+}
+
+type docQuery struct {
+	Page  int    `form:"page" validate:"required,min=1"`
+	Order string `json:"order"`
+}
+
+// lookup 沿着键依次取出嵌套的JSON对象或数组中的值，不存在时返回nil。
+func lookup(v interface{}, keys ...interface{}) interface{} {
+	for _, key := range keys {
+		switch k := key.(type) {
+		case string:
+			m, _ := v.(map[string]interface{})
+			v = m[k]
+		case int:
+			a, _ := v.([]interface{})
+			if k >= len(a) {
+				return nil
+			}
+			v = a[k]
+		}
+	}
+	return v
+}
+
+// decodeSpec 把生成的文档编码后重新解析，得到与客户端看到的一致的结构。
+func decodeSpec(t *testing.T, data []byte) map[string]interface{} {
+	t.Helper()
+	var doc map[string]interface{}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		t.Fatalf("invalid OpenAPI document: %v\n%s", err, data)
+	}
+	return doc
+}
+
+func newDocEngine() *Engine {
+	r := New()
+	r.GET("/users/:id<int>", func(c *Context) {})
+	r.Handle(http.MethodDelete, "/users/:id<int>", func(c *Context) {})
+	r.POST("/users", func(c *Context) {})
+	r.GET("/files/:name.:ext", func(c *Context) {})
+	r.GET("/objects/:key<uuid>", func(c *Context) {})
+	r.GET("/tags/:tag<[a-z]+>", func(c *Context) {})
+	r.GET("/assets/*filepath", func(c *Context) {})
+	r.Handle("PURGE", "/cache", func(c *Context) {})
+	r.Group("/legacy").Mount("/app", http.NotFoundHandler())
+	v1 := r.Group("/v1")
+	v1.GET("/users", func(c *Context) {})
+	v1.Doc(http.MethodGet, "/users", Operation{
+		Summary:   "List users",
+		Tags:      []string{"users"},
+		Query:     docQuery{},
+		Responses: map[int]interface{}{http.StatusOK: []docUser{}},
+	})
+	r.Doc(http.MethodPost, "/users", Operation{
+		OperationID: "createUser",
+		Deprecated:  true,
+		Request:     docUser{},
+		Responses:   map[int]interface{}{http.StatusCreated: &docUser{}, http.StatusBadRequest: nil},
+	})
+	return r
+}
+
+func TestOpenAPIPaths(t *testing.T) {
+	r := newDocEngine()
+	r.OpenAPI("/openapi.json", OpenAPIConfig{Title: "Users", Servers: []string{"https://api.example.com"}})
+	w := performRequest(r, http.MethodGet, "/openapi.json", nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("GET /openapi.json = %d", w.Code)
+	}
+	doc := decodeSpec(t, w.Body.Bytes())
+
+	if lookup(doc, "openapi") != "3.0.3" || lookup(doc, "info", "title") != "Users" || lookup(doc, "info", "version") != "1.0.0" {
+		t.Errorf("header = %v %v", lookup(doc, "openapi"), lookup(doc, "info"))
+	}
+	for _, path := range []string{"/openapi.json", "/cache", "/legacy/app", "/legacy/app/{gee_mount_path}"} {
+		if lookup(doc, "paths", path) != nil {
+			t.Errorf("%s should not be documented", path)
+		}
+	}
+	if got := lookup(doc, "servers", 0, "url"); got != "https://api.example.com" {
+		t.Errorf("servers = %v", lookup(doc, "servers"))
+	}
+
+	users := lookup(doc, "paths", "/users/{id}")
+	if lookup(users, "get") == nil || lookup(users, "delete") == nil {
+		t.Fatalf("/users/{id} = %v", users)
+	}
+	params := map[string]interface{}{
+		"/users/{id}":        map[string]interface{}{"type": "integer"},
+		"/objects/{key}":     map[string]interface{}{"type": "string", "format": "uuid"},
+		"/tags/{tag}":        map[string]interface{}{"type": "string", "pattern": "^(?:[a-z]+)$"},
+		"/assets/{filepath}": map[string]interface{}{"type": "string"},
+	}
+	for path, want := range params {
+		if got := lookup(doc, "paths", path, "get", "parameters", 0, "schema"); !reflect.DeepEqual(got, want) {
+			t.Errorf("%s parameter schema = %v, want %v", path, got, want)
+		}
+	}
+	if got := lookup(doc, "paths", "/files/{name}.{ext}", "get", "parameters", 1, "name"); got != "ext" {
+		t.Errorf("composite segment parameters = %v", lookup(doc, "paths", "/files/{name}.{ext}", "get", "parameters"))
+	}
+	if got := lookup(doc, "paths", "/users/{id}", "get", "responses", "200", "description"); got != "OK" {
+		t.Errorf("default response = %v", got)
+	}
+}
+
+func TestOpenAPISchemas(t *testing.T) {
+	data, err := newDocEngine().MarshalOpenAPI(OpenAPIConfig{
+		Include: func(route RouteInfo) bool { return route.Path != "/assets/*filepath" },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc := decodeSpec(t, data)
+	if lookup(doc, "paths", "/assets/{filepath}") != nil {
+		t.Error("Include did not filter /assets/*filepath")
+	}
+
+	list := lookup(doc, "paths", "/v1/users", "get")
+	if lookup(list, "summary") != "List users" || lookup(list, "tags", 0) != "users" {
+		t.Errorf("GET /v1/users = %v", list)
+	}
+	page := lookup(list, "parameters", 0)
+	if lookup(page, "name") != "page" || lookup(page, "in") != "query" || lookup(page, "required") != true ||
+		lookup(page, "schema", "minimum") != float64(1) {
+		t.Errorf("page parameter = %v", page)
+	}
+	if got := lookup(list, "parameters", 1, "name"); got != "order" {
+		t.Errorf("order parameter name = %v", got)
+	}
+	if got := lookup(list, "responses", "200", "content", "application/json", "schema", "items", "$ref"); got != "#/components/schemas/docUser" {
+		t.Errorf("list response schema = %v", got)
+	}
+
+	create := lookup(doc, "paths", "/users", "post")
+	if lookup(create, "operationId") != "createUser" || lookup(create, "deprecated") != true {
+		t.Errorf("POST /users = %v", create)
+	}
+	if lookup(create, "responses", "400", "content") != nil || lookup(create, "responses", "400", "description") != "Bad Request" {
+		t.Errorf("400 response = %v", lookup(create, "responses", "400"))
+	}
+
+	user := lookup(doc, "components", "schemas", "docUser")
+	props, _ := lookup(user, "properties").(map[string]interface{})
+	for _, name := range []string{"id", "name", "email", "role", "age", "friends", "created"} {
+		if props[name] == nil {
+			t.Errorf("property %s is missing in %v", name, props)
+		}
+	}
+	for _, name := range []string{"Ignored", "secret", "docBase"} {
+		if props[name] != nil {
+			t.Errorf("property %s should not be documented", name)
+		}
+	}
+	checks := map[string]interface{}{
+		"name":    map[string]interface{}{"type": "string", "minLength": float64(2), "maxLength": float64(20), "description": "Display name"},
+		"email":   map[string]interface{}{"type": "string", "format": "email"},
+		"role":    map[string]interface{}{"type": "string", "enum": []interface{}{"admin", "user"}},
+		"age":     map[string]interface{}{"type": "integer", "minimum": float64(0), "maximum": float64(150), "exclusiveMaximum": true},
+		"friends": map[string]interface{}{"type": "array", "items": map[string]interface{}{"$ref": "#/components/schemas/docUser"}},
+		"created": map[string]interface{}{"type": "string", "format": "date-time"},
+	}
+	for name, want := range checks {
+		if !reflect.DeepEqual(props[name], want) {
+			t.Errorf("property %s = %v, want %v", name, props[name], want)
+		}
+	}
+	if got := lookup(user, "required"); !reflect.DeepEqual(got, []interface{}{"name"}) {
+		t.Errorf("required = %v", got)
+	}
+}
+
+func TestRoutes(t *testing.T) {
+	r := New()
+	r.GET("/a", func(c *Context) {})
+	r.Group("/g").POST("/b", func(c *Context) {})
+	r.Mount("/m", http.NotFoundHandler())
+	want := []RouteInfo{{http.MethodGet, "/a"}, {http.MethodPost, "/g/b"}}
+	routes := r.Routes()
+	if !reflect.DeepEqual(routes, want) {
+		t.Fatalf("Routes() = %v, want %v", routes, want)
+	}
+	routes[0].Path = "/changed"
+	if r.Routes()[0].Path != "/a" {
+		t.Fatal("Routes() returned the engine's internal slice")
+	}
+}
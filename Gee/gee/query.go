@@ -0,0 +1,139 @@
+package gee
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// defaultMultipartMemory 是解析multipart表单时保存在内存中的最大字节数，超出部分写入临时文件。
+const defaultMultipartMemory = 32 << 20
+
+// initQueryCache 解析URL查询参数并缓存在Context中。
+func (c *Context) initQueryCache() {
+	if c.queryCache == nil {
+		if c.Req != nil && c.Req.URL != nil {
+			c.queryCache = c.Req.URL.Query()
+		} else {
+			c.queryCache = url.Values{}
+		}
+	}
+}
+
+// initFormCache 解析请求体表单并缓存在Context中，不包含URL查询参数。
+// 请求体超出大小限制时表单为空，引擎会在处理函数没有写出响应时返回413。
+func (c *Context) initFormCache() {
+	if c.formCache != nil {
+		return
+	}
+	c.formCache = url.Values{}
+	req := c.Req
+	if err := req.ParseMultipartForm(defaultMultipartMemory); err != nil && !errors.Is(err, http.ErrNotMultipart) {
+		return
+	}
+	if req.PostForm != nil {
+		c.formCache = req.PostForm
+	}
+}
+
+// GetQuery 返回查询参数中指定键的第一个值，以及该键是否存在。
+// 例如 /search?q=&page=2 中 GetQuery("q") 返回 ("", true)，GetQuery("sort") 返回 ("", false)。
+func (c *Context) GetQuery(key string) (string, bool) {
+	if values, ok := c.GetQueryArray(key); ok {
+		return values[0], true
+	}
+	return "", false
+}
+
+// DefaultQuery 返回查询参数中指定键的第一个值，键不存在时返回defaultValue。
+func (c *Context) DefaultQuery(key, defaultValue string) string {
+	if value, ok := c.GetQuery(key); ok {
+		return value
+	}
+	return defaultValue
+}
+
+// QueryArray 返回查询参数中指定键的全部值，例如 ?id=1&id=2 返回 ["1", "2"]。
+func (c *Context) QueryArray(key string) []string {
+	values, _ := c.GetQueryArray(key)
+	return values
+}
+
+// GetQueryArray 返回查询参数中指定键的全部值，以及该键是否至少有一个值。
+func (c *Context) GetQueryArray(key string) ([]string, bool) {
+	c.initQueryCache()
+	values, ok := c.queryCache[key]
+	return values, ok && len(values) > 0
+}
+
+// QueryMap 返回查询参数中以 key[name] 形式出现的键值对，
+// 例如 ?filter[name]=x&filter[age]=3 中 QueryMap("filter") 返回 {"name": "x", "age": "3"}。
+func (c *Context) QueryMap(key string) map[string]string {
+	m, _ := c.GetQueryMap(key)
+	return m
+}
+
+// GetQueryMap 与QueryMap相同，同时返回是否至少存在一个键值对。
+func (c *Context) GetQueryMap(key string) (map[string]string, bool) {
+	c.initQueryCache()
+	return bracketMap(c.queryCache, key)
+}
+
+// GetPostForm 返回请求体表单中指定键的第一个值，以及该键是否存在。
+func (c *Context) GetPostForm(key string) (string, bool) {
+	if values, ok := c.GetPostFormArray(key); ok {
+		return values[0], true
+	}
+	return "", false
+}
+
+// DefaultPostForm 返回请求体表单中指定键的第一个值，键不存在时返回defaultValue。
+func (c *Context) DefaultPostForm(key, defaultValue string) string {
+	if value, ok := c.GetPostForm(key); ok {
+		return value
+	}
+	return defaultValue
+}
+
+// PostFormArray 返回请求体表单中指定键的全部值。
+func (c *Context) PostFormArray(key string) []string {
+	values, _ := c.GetPostFormArray(key)
+	return values
+}
+
+// GetPostFormArray 返回请求体表单中指定键的全部值，以及该键是否至少有一个值。
+func (c *Context) GetPostFormArray(key string) ([]string, bool) {
+	c.initFormCache()
+	values, ok := c.formCache[key]
+	return values, ok && len(values) > 0
+}
+
+// PostFormMap 返回请求体表单中以 key[name] 形式出现的键值对。
+func (c *Context) PostFormMap(key string) map[string]string {
+	m, _ := c.GetPostFormMap(key)
+	return m
+}
+
+// GetPostFormMap 与PostFormMap相同，同时返回是否至少存在一个键值对。
+func (c *Context) GetPostFormMap(key string) (map[string]string, bool) {
+	c.initFormCache()
+	return bracketMap(c.formCache, key)
+}
+
+// bracketMap 从values中收集 key[name]=value 形式的参数，每个name取第一个值。
+func bracketMap(values url.Values, key string) (map[string]string, bool) {
+	m := make(map[string]string)
+	prefix := key + "["
+	for k, v := range values {
+		if len(v) == 0 || !strings.HasPrefix(k, prefix) {
+			continue
+		}
+		end := strings.IndexByte(k[len(prefix):], ']')
+		if end < 0 {
+			continue
+		}
+		m[k[len(prefix):len(prefix)+end]] = v[0]
+	}
+	return m, len(m) > 0
+}
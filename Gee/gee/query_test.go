@@ -0,0 +1,99 @@
+package gee
+
+import (
+	"bytes"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// formContext 创建一个带有URL查询参数和urlencoded请求体的Context。
+func formContext(target, body string) *Context {
+	req := httptest.NewRequest(http.MethodPost, target, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return newContext(httptest.NewRecorder(), req)
+}
+
+func TestQueryAccessors(t *testing.T) {
+	c := formContext("/search?q=&page=2&id=1&id=2&filter[name]=x&filter[age]=3&filter=bad", "")
+
+	if v, ok := c.GetQuery("q"); v != "" || !ok {
+		t.Errorf("GetQuery(q) = %q, %v", v, ok)
+	}
+	if v, ok := c.GetQuery("sort"); v != "" || ok {
+		t.Errorf("GetQuery(sort) = %q, %v", v, ok)
+	}
+	if v := c.Query("page"); v != "2" {
+		t.Errorf("Query(page) = %q", v)
+	}
+	if v := c.DefaultQuery("sort", "name"); v != "name" {
+		t.Errorf("DefaultQuery(sort) = %q", v)
+	}
+	if v := c.DefaultQuery("q", "default"); v != "" {
+		t.Errorf("DefaultQuery(q) = %q, want the empty value", v)
+	}
+	if v := c.QueryArray("id"); !reflect.DeepEqual(v, []string{"1", "2"}) {
+		t.Errorf("QueryArray(id) = %v", v)
+	}
+	if v, ok := c.GetQueryMap("filter"); !ok || !reflect.DeepEqual(v, map[string]string{"name": "x", "age": "3"}) {
+		t.Errorf("GetQueryMap(filter) = %v, %v", v, ok)
+	}
+	if _, ok := c.GetQueryMap("missing"); ok {
+		t.Error("GetQueryMap(missing) reported values")
+	}
+
+	c.Req.URL.RawQuery = "page=3"
+	if v := c.Query("page"); v != "2" {
+		t.Errorf("Query(page) after changing the URL = %q, want the cached value", v)
+	}
+}
+
+func TestPostFormAccessors(t *testing.T) {
+	c := formContext("/submit?id=9&only=query&name=fromquery", "name=gee&tag=a&tag=b&m[k]=v&empty=")
+
+	if v := c.PostForm("name"); v != "gee" {
+		t.Errorf("PostForm(name) = %q, want the body value", v)
+	}
+	if v := c.PostForm("only"); v != "query" {
+		t.Errorf("PostForm(only) = %q, want the query value", v)
+	}
+	if v := c.PostForm("missing"); v != "" {
+		t.Errorf("PostForm(missing) = %q", v)
+	}
+	if v, ok := c.GetPostForm("only"); v != "" || ok {
+		t.Errorf("GetPostForm(only) = %q, %v, want only the body form", v, ok)
+	}
+	if v, ok := c.GetPostForm("empty"); v != "" || !ok {
+		t.Errorf("GetPostForm(empty) = %q, %v", v, ok)
+	}
+	if v := c.DefaultPostForm("id", "none"); v != "none" {
+		t.Errorf("DefaultPostForm(id) = %q", v)
+	}
+	if v := c.PostFormArray("tag"); !reflect.DeepEqual(v, []string{"a", "b"}) {
+		t.Errorf("PostFormArray(tag) = %v", v)
+	}
+	if v := c.PostFormMap("m"); !reflect.DeepEqual(v, map[string]string{"k": "v"}) {
+		t.Errorf("PostFormMap(m) = %v", v)
+	}
+}
+
+func TestPostFormMultipart(t *testing.T) {
+	var buf bytes.Buffer
+	mw := multipart.NewWriter(&buf)
+	mw.WriteField("title", "notes")
+	mw.WriteField("tag", "a")
+	mw.WriteField("tag", "b")
+	mw.Close()
+
+	r := New()
+	r.POST("/upload", func(c *Context) {
+		c.String(http.StatusOK, "%s %v %s", c.PostForm("title"), c.PostFormArray("tag"), c.PostForm("page"))
+	})
+	w := performRequest(r, http.MethodPost, "/upload?page=1", &buf, "Content-Type", mw.FormDataContentType())
+	if w.Body.String() != "notes [a b] 1" {
+		t.Fatalf("multipart form = %q", w.Body)
+	}
+}
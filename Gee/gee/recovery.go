@@ -1,44 +1,133 @@
 package gee
 
 import (
+	"errors"
 	"fmt"
+	"io"
 	"log"
+	"net"
 	"net/http"
+	"os"
 	"runtime"
 	"strings"
+	"syscall"
 )
 
+// defaultStackDepth 是记录panic堆栈时默认采集的最大帧数。
+const defaultStackDepth = 32
+
+// RecoveryFunc 是自定义的panic恢复处理函数。
+// err 为recover()得到的原始值，处理函数负责向客户端写出响应。
+type RecoveryFunc func(c *Context, err interface{})
+
+// RecoveryConfig 用于配置Recovery中间件的行为。
+type RecoveryConfig struct {
+	// Handler 自定义恢复处理函数，为nil时返回500错误。
+	Handler RecoveryFunc
+	// Output 堆栈日志的输出位置，为nil时使用log包的默认输出。
+	Output io.Writer
+	// StackDepth 记录的最大堆栈帧数，小于等于0时使用默认值32。
+	StackDepth int
+	// HideDetails 为true时不把panic信息返回给客户端，只返回通用的错误文本。
+	// 为false时也只在DebugMode下返回panic信息，其他模式下总是隐藏。
+	HideDetails bool
+}
+
 // trace 函数用于获取并返回触发 panic 时的堆栈信息。
-// 参数 message 为 panic 时附加的信息。
+// 参数 message 为 panic 时附加的信息，depth 为最多采集的堆栈帧数。
 // 返回值为拼接了 panic 信息和堆栈跟踪的字符串。
-func trace(message string) string {
-    var pcs [32]uintptr
-		// 获取当前调用栈的信息，存储到pcs中
-    n := runtime.Callers(3, pcs[:]) // 获取调用 trace 函数的调用栈信息
-		// 创建一个strings.Builder对象str，用于构建返回的字符串。
-    var str strings.Builder
-    str.WriteString(message + "\nTraceback:") // 开始构建返回的字符串，包含 panic 信息和 traceback 标题
-    for _, pc := range pcs[:n] { // 遍历调用栈信息
-        fn := runtime.FuncForPC(pc) // 获取函数信息
-        file, line := fn.FileLine(pc) // 获取文件和行号信息
-        str.WriteString(fmt.Sprintf("\n%s:%d ", file, line)) // 将文件和行号添加到字符串中
-    }
-    return str.String() // 返回构建完成的字符串
+func trace(message string, depth int) string {
+	pcs := make([]uintptr, depth)
+	// 获取当前调用栈的信息，存储到pcs中
+	n := runtime.Callers(3, pcs) // 获取调用 trace 函数的调用栈信息
+	// 创建一个strings.Builder对象str，用于构建返回的字符串。
+	var str strings.Builder
+	str.WriteString(message + "\nTraceback:") // 开始构建返回的字符串，包含 panic 信息和 traceback 标题
+	for _, pc := range pcs[:n] {              // 遍历调用栈信息
+		fn := runtime.FuncForPC(pc)                          // 获取函数信息
+		file, line := fn.FileLine(pc)                        // 获取文件和行号信息
+		str.WriteString(fmt.Sprintf("\n%s:%d ", file, line)) // 将文件和行号添加到字符串中
+	}
+	return str.String() // 返回构建完成的字符串
+}
+
+// isBrokenPipe 判断panic的值是否由客户端断开连接引起（EPIPE或连接被重置）。
+// 这种情况下连接已经不可写，不应再尝试向客户端写出响应。
+func isBrokenPipe(err interface{}) bool {
+	e, ok := err.(error)
+	if !ok {
+		return false
+	}
+	if errors.Is(e, syscall.EPIPE) || errors.Is(e, syscall.ECONNRESET) {
+		return true
+	}
+	var ne *net.OpError
+	if errors.As(e, &ne) {
+		var se *os.SyscallError
+		if errors.As(ne, &se) {
+			msg := strings.ToLower(se.Error())
+			return strings.Contains(msg, "broken pipe") || strings.Contains(msg, "connection reset by peer")
+		}
+	}
+	return false
 }
 
 // Recovery 函数返回一个处理程序（Handlerfunc），该处理程序用于recover从HTTP请求处理中引发的panic，并返回500错误。
 // 返回的处理函数类型可直接用于类似Gin或Echo等HTTP框架的路由处理中。
 func Recovery() Handlerfunc {
-    return func(c *Context) {
-        defer func() { // 使用defer确保在panic时执行以下逻辑
-            if err := recover(); err != nil { // 捕获并处理panic
-                message := fmt.Sprintf("%s", err) // 将panic的内容转换为字符串
-                log.Printf("%s\n\n", trace(message)) // 使用trace函数获取堆栈信息并记录到日志
-                c.Fail(http.StatusInternalServerError, message) // 向客户端返回500错误信息
-            }
-        }()
-
-        c.Next() // 继续执行后续的处理函数
-
-    }
-}
\ No newline at end of file
+	return RecoveryWithConfig(RecoveryConfig{})
+}
+
+// RecoveryWithConfig 按照给定的配置创建Recovery中间件。
+// 当panic由客户端断开连接引起时，只记录日志并终止处理链，不再写出响应。
+// http.ErrAbortHandler会被重新panic，交给net/http中止响应。
+func RecoveryWithConfig(conf RecoveryConfig) Handlerfunc {
+	logger := log.Default()
+	if conf.Output != nil {
+		logger = log.New(conf.Output, "", log.LstdFlags)
+	}
+	depth := conf.StackDepth
+	if depth <= 0 {
+		depth = defaultStackDepth
+	}
+	hide := conf.HideDetails || !IsDebugging()
+	handler := conf.Handler
+	if handler == nil {
+		handler = func(c *Context, err interface{}) {
+			message := fmt.Sprintf("%s", err) // 将panic的内容转换为字符串
+			if hide {
+				message = http.StatusText(http.StatusInternalServerError)
+			}
+			c.Fail(http.StatusInternalServerError, message) // 向客户端返回500错误信息
+		}
+	}
+	return func(c *Context) {
+		defer func() { // 使用defer确保在panic时执行以下逻辑
+			err := recover()
+			if err == nil {
+				return
+			}
+			if err == http.ErrAbortHandler {
+				panic(err) // 处理函数主动中止响应，交给net/http处理
+			}
+			message := fmt.Sprintf("%s", err) // 将panic的内容转换为字符串
+			// 测试模式下不输出默认日志
+			quiet := conf.Output == nil && Mode() == TestMode
+			if isBrokenPipe(err) {
+				// 客户端已经断开，记录后直接结束处理链
+				if !quiet {
+					logger.Printf("%s %s: connection closed by client: %s\n\n", c.Method, c.Path, message)
+				}
+				c.Abort()
+				return
+			}
+			if !quiet {
+				logger.Printf("%s\n\n", trace(message, depth)) // 获取堆栈信息并记录到日志
+			}
+			handler(c, err)
+			c.Abort()
+		}()
+
+		c.Next() // 继续执行后续的处理函数
+	}
+}
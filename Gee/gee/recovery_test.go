@@ -0,0 +1,99 @@
+package gee
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"strings"
+	"syscall"
+	"testing"
+)
+
+func TestRecoveryHidesDetailsOutsideDebugMode(t *testing.T) {
+	r := New()
+	r.Use(Recovery())
+	r.GET("/panic", func(c *Context) { panic("secret dsn") })
+
+	w := performRequest(r, http.MethodGet, "/panic", nil)
+	if w.Code != http.StatusInternalServerError {
+		t.Fatalf("status = %d, want 500", w.Code)
+	}
+	if strings.Contains(w.Body.String(), "secret dsn") {
+		t.Fatalf("panic details leaked: %s", w.Body.String())
+	}
+}
+
+func TestRecoveryShowsDetailsInDebugMode(t *testing.T) {
+	var buf bytes.Buffer
+	withMode(DebugMode, func() {
+		r := New()
+		r.Use(RecoveryWithConfig(RecoveryConfig{Output: &buf}))
+		r.GET("/panic", func(c *Context) { panic("boom") })
+
+		w := performRequest(r, http.MethodGet, "/panic", nil)
+		if !strings.Contains(w.Body.String(), "boom") {
+			t.Fatalf("body = %s, want panic message", w.Body.String())
+		}
+	})
+	if !strings.Contains(buf.String(), "Traceback:") {
+		t.Fatalf("log = %s, want stack trace", buf.String())
+	}
+}
+
+func TestRecoveryHideDetails(t *testing.T) {
+	withMode(DebugMode, func() {
+		r := New()
+		r.Use(RecoveryWithConfig(RecoveryConfig{HideDetails: true, Output: &bytes.Buffer{}}))
+		r.GET("/panic", func(c *Context) { panic("boom") })
+
+		w := performRequest(r, http.MethodGet, "/panic", nil)
+		if strings.Contains(w.Body.String(), "boom") {
+			t.Fatalf("panic details leaked: %s", w.Body.String())
+		}
+	})
+}
+
+func TestRecoveryCustomHandler(t *testing.T) {
+	r := New()
+	r.Use(RecoveryWithConfig(RecoveryConfig{
+		Handler: func(c *Context, err interface{}) {
+			c.String(http.StatusServiceUnavailable, "recovered: %v", err)
+		},
+	}))
+	r.GET("/panic", func(c *Context) { panic("x") })
+
+	w := performRequest(r, http.MethodGet, "/panic", nil)
+	if w.Code != http.StatusServiceUnavailable || w.Body.String() != "recovered: x" {
+		t.Fatalf("got %d %q", w.Code, w.Body.String())
+	}
+}
+
+func TestRecoveryBrokenPipe(t *testing.T) {
+	called := false
+	r := New()
+	r.Use(RecoveryWithConfig(RecoveryConfig{
+		Handler: func(c *Context, err interface{}) { called = true },
+	}))
+	r.GET("/pipe", func(c *Context) { panic(syscall.EPIPE) })
+
+	performRequest(r, http.MethodGet, "/pipe", nil)
+	if called {
+		t.Fatal("handler called for a broken pipe")
+	}
+	if !isBrokenPipe(errors.Join(errors.New("write"), syscall.ECONNRESET)) {
+		t.Fatal("ECONNRESET not detected")
+	}
+}
+
+func TestRecoveryRepanicsErrAbortHandler(t *testing.T) {
+	r := New()
+	r.Use(Recovery())
+	r.GET("/abort", func(c *Context) { panic(http.ErrAbortHandler) })
+
+	defer func() {
+		if err := recover(); err != http.ErrAbortHandler {
+			t.Fatalf("recovered %v, want http.ErrAbortHandler", err)
+		}
+	}()
+	performRequest(r, http.MethodGet, "/abort", nil)
+}
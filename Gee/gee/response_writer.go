@@ -0,0 +1,104 @@
+package gee
+
+import (
+	"bufio"
+	"errors"
+	"net"
+	"net/http"
+)
+
+// noWritten 表示响应还没有写出任何内容。
+const noWritten = -1
+
+// ResponseWriter 在http.ResponseWriter的基础上记录状态码和写出的字节数，
+// 并统一提供Flush和Hijack，便于中间件包装后仍能支持流式响应和协议升级。
+type ResponseWriter interface {
+	http.ResponseWriter
+	http.Flusher
+	http.Hijacker
+
+	// Status 返回已写出的状态码，未写出时返回200。
+	Status() int
+	// Size 返回已写出的响应体字节数，未写出时返回-1。
+	Size() int
+	// Written 返回响应头是否已经写出。
+	Written() bool
+	// WriteHeaderNow 立即写出响应头。
+	WriteHeaderNow()
+	// Unwrap 返回被包装的http.ResponseWriter，供http.ResponseController使用。
+	Unwrap() http.ResponseWriter
+}
+
+// responseWriter 是ResponseWriter的默认实现。
+type responseWriter struct {
+	http.ResponseWriter
+	size   int
+	status int
+}
+
+var _ ResponseWriter = (*responseWriter)(nil)
+
+// reset 让responseWriter包装新的http.ResponseWriter。
+func (w *responseWriter) reset(writer http.ResponseWriter) {
+	w.ResponseWriter = writer
+	w.size = noWritten
+	w.status = http.StatusOK
+}
+
+// WriteHeader 记录状态码并写出响应头，重复调用会被忽略。
+func (w *responseWriter) WriteHeader(code int) {
+	if code > 0 && !w.Written() {
+		w.status = code
+		w.size = 0
+		w.ResponseWriter.WriteHeader(code)
+	}
+}
+
+func (w *responseWriter) WriteHeaderNow() {
+	if !w.Written() {
+		w.WriteHeader(w.status)
+	}
+}
+
+func (w *responseWriter) Write(data []byte) (n int, err error) {
+	w.WriteHeaderNow()
+	n, err = w.ResponseWriter.Write(data)
+	w.size += n
+	return
+}
+
+func (w *responseWriter) Status() int {
+	return w.status
+}
+
+func (w *responseWriter) Size() int {
+	return w.size
+}
+
+func (w *responseWriter) Written() bool {
+	return w.size != noWritten
+}
+
+// Flush 写出响应头并把缓冲的数据发送给客户端。
+func (w *responseWriter) Flush() {
+	w.WriteHeaderNow()
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Hijack 接管底层连接，之后响应不再经过http包处理。
+func (w *responseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("gee: the ResponseWriter doesn't support hijacking")
+	}
+	if w.size < 0 {
+		w.size = 0
+	}
+	return h.Hijack()
+}
+
+func (w *responseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
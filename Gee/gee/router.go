@@ -1,10 +1,12 @@
 package gee
 
 import (
-	"net/http"
 	"strings"
 )
 
+// anyMethod 是不区分请求方法的路由使用的方法名，请求方法没有匹配的路由时再从中查找。
+const anyMethod = "*"
+
 type router struct {
 	roots   map[string]*node
 	handler map[string]Handlerfunc
@@ -16,26 +18,38 @@ func newRouter() *router {
 		handler: make(map[string]Handlerfunc),
 	}
 }
+// match 根据请求的方法和路径查找匹配的路由，把路由参数、路由模式和路由键记录到上下文中。
+// 查找只进行一次，请求体限制和handle都使用这里的结果。
+func (r *router) match(c *Context) {
+	// 根据请求方法和路径获取匹配的路由和参数。
+	method := c.Method
+	n, params := r.getRoute(method, c.Path)
+	if n == nil {
+		// 再查找不区分请求方法的路由，例如Mount的挂载点。
+		method = anyMethod
+		n, params = r.getRoute(method, c.Path)
+	}
+	if n != nil {
+		c.setParams(params) // 将匹配到的参数设置到上下文对象中。
+		c.fullPath = n.pattern // 记录匹配到的路由模式。
+		c.routeKey = method + "-" + n.pattern // 记录路由键，用于获取处理函数和单个路由的设置。
+	}
+}
+
 // handle 是一个处理HTTP请求的方法。
-// 它根据请求的方法和路径来查找对应的路由，并执行相应的处理函数。
-// 如果找到了匹配的路由，则执行对应的处理函数；如果没有找到，则返回404页面。
+// 它根据match找到的路由执行相应的处理函数。
+// 如果找到了匹配的路由，则执行对应的处理函数；如果没有找到，则交给NoRoute或NoMethod处理函数，默认返回404页面。
 //
 // 参数:
 // - r *router: 是路由对象，用于存储和查找路由信息。
 // - c *Context: 是上下文对象，包含了当前HTTP请求的方法、路径以及参数等信息。
 func (r *router) handle(c *Context) {
-	// 根据请求方法和路径获取匹配的路由和参数。
-	n, params := r.getRoute(c.Method, c.Path)
-	if n != nil {
-		// 如果找到了匹配的路由，构建键并从路由处理器映射中获取对应的处理函数。
-		key := c.Method + "-" + n.pattern
-		c.Params = params // 将匹配到的参数设置到上下文对象中。
-		c.handler = append(c.handler, r.handler[key]) // 将处理函数添加到上下文对象的处理器链中。
+	if c.routeKey != "" {
+		// 如果找到了匹配的路由，从路由处理器映射中获取对应的处理函数。
+		c.handler = append(c.handler, r.handler[c.routeKey]) // 将处理函数添加到上下文对象的处理器链中。
 	} else {
-		// 如果没有找到匹配的路由，添加一个返回404状态码的处理函数。
-		c.handler = append(c.handler, func(c *Context) {
-			c.String(http.StatusNotFound, "404 NOT FOUND: %s\n", c.Path)
-		})
+		// 如果没有找到匹配的路由，添加所属分组的NoMethod或NoRoute处理函数。
+		c.handler = append(c.handler, r.fallback(c)...)
 	}
 	// 调用下一个处理函数，继续处理请求。
 	c.Next()
@@ -67,13 +81,19 @@ func parsePattern(pattern string) []string {
 // pattern: 路径模式，用于匹配请求的URL路径。
 // handler: 与该路由匹配时执行的处理函数。
 func (r *router) addRoute(method string, pattern string, handler Handlerfunc) {
+	// 参数约束只作用于一个路径段，需要在拆分路径段之前检查。
+	checkConstraints(pattern)
 	// 解析路径模式为更易处理的格式。
 	parts := parsePattern(pattern)
+	// * 通配符会匹配剩余的全部路径，之后不能再有其他路径段。
+	if len(parts) > 0 && parts[len(parts)-1][0] == '*' && !strings.HasSuffix(strings.TrimRight(pattern, "/"), parts[len(parts)-1]) {
+		panic("gee: catch-all parameter must be the last segment in route '" + pattern + "'")
+	}
 	// 生成唯一的键，用于存储路由信息。
 	key := method + "-" + pattern
 	// 检查是否存在根节点，若不存在则创建。
 	_, ok := r.roots[method]
-	if ok {
+	if !ok {
 		r.roots[method] = &node{}
 	}
 	// 将路由模式插入到树结构中，以便快速匹配。
@@ -106,9 +126,15 @@ func (r *router) getRoute(method string, path string) (*node, map[string]string)
 	if node != nil {
 		parts := parsePattern(node.pattern) // 解析匹配到的节点的模式，用于进一步提取参数。
 		for index, part := range parts {
+			if isCompositeSegment(part) {
+				// 混合了字面量和参数的路径段，通过正则表达式提取其中的各个参数。
+				compileSegment(part).extract(searchParts[index], params)
+				continue
+			}
 			if part[0] == ':' {
 				// 如果模式部分以冒号开头，表示这是一个命名参数，将其映射到对应的路径部分。
-				params[part[1:]] = searchParts[index]
+				name, _ := splitParam(part)
+				params[name] = searchParts[index]
 			}
 			if part[0] == '*' && len(part) > 1 {
 				// 如果模式部分以星号开头，表示这是一个通配符参数，将其映射到剩余的所有路径部分。
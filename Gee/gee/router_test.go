@@ -0,0 +1,126 @@
+package gee
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// routeCase 是一次请求及其期望的响应体。
+type routeCase struct {
+	path, body string
+}
+
+func checkRoutes(t *testing.T, r *Engine, cases []routeCase) {
+	t.Helper()
+	for _, tt := range cases {
+		w := performRequest(r, http.MethodGet, tt.path, nil)
+		if w.Body.String() != tt.body {
+			t.Errorf("GET %s = %d %q, want %q", tt.path, w.Code, w.Body.String(), tt.body)
+		}
+	}
+}
+
+// mustPanic 断言fn panic，并且panic信息包含want。
+func mustPanic(t *testing.T, want string, fn func()) {
+	t.Helper()
+	defer func() {
+		t.Helper()
+		err := recover()
+		if err == nil {
+			t.Errorf("no panic, want %q", want)
+			return
+		}
+		if msg, _ := err.(string); !strings.Contains(msg, want) {
+			t.Errorf("panic %v, want it to contain %q", err, want)
+		}
+	}()
+	fn()
+}
+
+func TestParamConstraints(t *testing.T) {
+	r := New()
+	r.GET("/users/:id<int>", func(c *Context) {
+		id, err := c.ParamInt("id")
+		c.String(http.StatusOK, "int %d %v", id, err)
+	})
+	r.GET("/users/me", func(c *Context) { c.String(http.StatusOK, "me") })
+	r.GET("/users/:name", func(c *Context) { c.String(http.StatusOK, "name %s", c.Param("name")) })
+	r.GET("/posts/:slug<[a-z-]+>", func(c *Context) { c.String(http.StatusOK, "slug %s", c.Param("slug")) })
+	r.GET("/objects/:id<uuid>/meta", func(c *Context) { c.String(http.StatusOK, "uuid %s", c.Param("id")) })
+
+	checkRoutes(t, r, []routeCase{
+		{"/users/12", "int 12 <nil>"},
+		{"/users/-3", "int -3 <nil>"},
+		{"/users/me", "me"},
+		{"/users/bob", "name bob"},
+		{"/posts/hello-gee", "slug hello-gee"},
+		{"/posts/Hello", "404 NOT FOUND: /posts/Hello\n"},
+		{"/objects/123e4567-e89b-12d3-a456-426614174000/meta", "uuid 123e4567-e89b-12d3-a456-426614174000"},
+		{"/objects/12/meta", "404 NOT FOUND: /objects/12/meta\n"},
+	})
+}
+
+func TestParamConversions(t *testing.T) {
+	c := newContext(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	c.setParams(map[string]string{"n": "42", "f": "1.5", "bad": "x"})
+	if n, err := c.ParamInt64("n"); n != 42 || err != nil {
+		t.Fatalf("ParamInt64 = %d, %v", n, err)
+	}
+	if n, err := c.ParamUint64("n"); n != 42 || err != nil {
+		t.Fatalf("ParamUint64 = %d, %v", n, err)
+	}
+	if f, err := c.ParamFloat64("f"); f != 1.5 || err != nil {
+		t.Fatalf("ParamFloat64 = %v, %v", f, err)
+	}
+	if _, err := c.ParamInt("bad"); err == nil {
+		t.Fatal("ParamInt accepted a non-number")
+	}
+}
+
+func TestInvalidConstraints(t *testing.T) {
+	r := New()
+	mustPanic(t, "invalid route parameter constraint", func() { r.GET("/a/:id<[>", func(c *Context) {}) })
+	mustPanic(t, "must not contain '/'", func() { r.GET("/b/:p<a/b>", func(c *Context) {}) })
+	mustPanic(t, "must not contain '/'", func() { r.GET("/c/v:p<[0-9/]+>.json", func(c *Context) {}) })
+}
+
+func TestCompositeSegments(t *testing.T) {
+	r := New()
+	r.GET("/files/:name.:ext", func(c *Context) {
+		c.String(http.StatusOK, "%s %s", c.Param("name"), c.Param("ext"))
+	})
+	r.GET("/@:user", func(c *Context) { c.String(http.StatusOK, "user %s", c.Param("user")) })
+	r.GET("/assets/v:version<[0-9]*>/*path", func(c *Context) {
+		c.String(http.StatusOK, "v%s %s", c.Param("version"), c.Param("path"))
+	})
+
+	checkRoutes(t, r, []routeCase{
+		{"/files/archive.tar.gz", "archive.tar gz"},
+		{"/files/readme", "404 NOT FOUND: /files/readme\n"},
+		{"/@gee", "user gee"},
+		{"/assets/v12/css/site.css", "v12 css/site.css"},
+		{"/assets/vx/site.css", "404 NOT FOUND: /assets/vx/site.css\n"},
+	})
+}
+
+func TestInvalidSegments(t *testing.T) {
+	r := New()
+	mustPanic(t, "catch-all parameter is not allowed", func() { r.GET("/a/v*:x", func(c *Context) {}) })
+	mustPanic(t, "catch-all parameter is not allowed", func() { r.GET("/b/:x<[a-z]+>.*", func(c *Context) {}) })
+	mustPanic(t, "must be separated by a literal", func() { r.GET("/c/:a:b", func(c *Context) {}) })
+	mustPanic(t, "catch-all parameter must be the last segment", func() { r.GET("/d/*rest/x", func(c *Context) {}) })
+}
+
+func TestRouteConflicts(t *testing.T) {
+	r := New()
+	r.GET("/a", func(c *Context) { c.String(http.StatusOK, "a") })
+	r.GET("/a/", func(c *Context) { c.String(http.StatusOK, "a/") })
+	checkRoutes(t, r, []routeCase{{"/a", "a/"}, {"/a/", "a/"}})
+
+	r.GET("/q/:a", func(c *Context) {})
+	mustPanic(t, "conflicts with existing route '/q/:a'", func() { r.GET("/q/:b", func(c *Context) {}) })
+	r.GET("/f/:name.:ext", func(c *Context) {})
+	mustPanic(t, "conflicts with existing route", func() { r.GET("/f/:base.:suffix", func(c *Context) {}) })
+}
@@ -0,0 +1,128 @@
+package gee
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// 一个路径段中可以混合字面量和多个参数，例如 /files/:name.:ext、/assets/v:version/*path、/@:user。
+// 匹配规则：
+//   - 路径段不以":"开头、或包含多个参数、或参数约束之后还有字面量时，按混合路径段处理；
+//     以":"开头且只有一个参数的路径段仍按普通参数处理，参数名为":"之后的全部内容。
+//   - 每个参数至少匹配一个字符，没有约束的参数尽量多地匹配，
+//     因此 :name.:ext 匹配 archive.tar.gz 时 name 为 archive.tar，ext 为 gz。
+//   - 相邻的两个参数之间必须有字面量分隔，* 通配符不能出现在混合路径段中。
+//   - 查找时静态路径段优先，其次是混合路径段，然后是带约束的参数、普通参数和 * 通配符。
+
+// segmentPattern 是编译后的混合路径段。
+type segmentPattern struct {
+	re      *regexp.Regexp
+	names   []string // names 为各参数的名称
+	indexes []int    // indexes 为各参数在正则表达式中的分组下标
+	shape   string   // shape 为去掉参数名后的形状，形状相同的路径段匹配的路径完全相同
+}
+
+// segmentCache 缓存已经编译的混合路径段，查找时提取参数会重复用到。
+var segmentCache sync.Map
+
+// isParamNameChar 判断字符能否出现在混合路径段的参数名中。
+func isParamNameChar(c byte) bool {
+	return c == '_' || ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z') || ('0' <= c && c <= '9')
+}
+
+// isCompositeSegment 判断路径段是否需要按混合路径段处理。
+func isCompositeSegment(part string) bool {
+	if part == "" || part[0] == '*' || strings.IndexByte(part, ':') < 0 {
+		return false
+	}
+	if part[0] != ':' || strings.Count(part, ":") > 1 {
+		return true
+	}
+	return strings.IndexByte(part, '<') >= 0 && !strings.HasSuffix(part, ">")
+}
+
+// compileSegment 编译混合路径段，格式错误时panic。
+func compileSegment(part string) *segmentPattern {
+	if v, ok := segmentCache.Load(part); ok {
+		return v.(*segmentPattern)
+	}
+	seg := &segmentPattern{}
+	var expr, shape strings.Builder
+	expr.WriteString("^")
+	lastParam := false
+	for i := 0; i < len(part); {
+		if part[i] != ':' {
+			j := strings.IndexByte(part[i:], ':')
+			if j < 0 {
+				j = len(part) - i
+			}
+			// 约束中的正则表达式可以包含"*"，字面量中的"*"只能是写错位置的通配符
+			if strings.IndexByte(part[i:i+j], '*') >= 0 {
+				panic("gee: catch-all parameter is not allowed inside path segment '" + part + "'")
+			}
+			expr.WriteString(regexp.QuoteMeta(part[i : i+j]))
+			shape.WriteString(part[i : i+j])
+			i += j
+			lastParam = false
+			continue
+		}
+		if lastParam {
+			panic("gee: parameters must be separated by a literal in path segment '" + part + "'")
+		}
+		j := i + 1
+		for j < len(part) && isParamNameChar(part[j]) {
+			j++
+		}
+		if j == i+1 {
+			panic("gee: missing parameter name in path segment '" + part + "'")
+		}
+		name, constraint := part[i+1:j], ""
+		if j < len(part) && part[j] == '<' {
+			end := strings.IndexByte(part[j:], '>')
+			if end < 0 {
+				panic("gee: unclosed parameter constraint in path segment '" + part + "'")
+			}
+			constraint = part[j+1 : j+end]
+			j += end + 1
+		}
+		group := "gee" + strconv.Itoa(len(seg.names))
+		if constraint == "" {
+			expr.WriteString("(?P<" + group + ">.+)")
+			shape.WriteString(":")
+		} else {
+			if builtin, ok := paramConstraints[constraint]; ok {
+				constraint = builtin
+			}
+			expr.WriteString("(?P<" + group + ">" + constraint + ")")
+			shape.WriteString(":<" + constraint + ">")
+		}
+		seg.names = append(seg.names, name)
+		i = j
+		lastParam = true
+	}
+	expr.WriteString("$")
+	re, err := regexp.Compile(expr.String())
+	if err != nil {
+		panic("gee: invalid path segment '" + part + "': " + err.Error())
+	}
+	seg.re, seg.shape = re, shape.String()
+	for i := range seg.names {
+		seg.indexes = append(seg.indexes, re.SubexpIndex("gee"+strconv.Itoa(i)))
+	}
+	segmentCache.Store(part, seg)
+	return seg
+}
+
+// extract 从路径段中提取参数，路径段不匹配时返回false。
+func (seg *segmentPattern) extract(value string, params map[string]string) bool {
+	m := seg.re.FindStringSubmatch(value)
+	if m == nil {
+		return false
+	}
+	for i, name := range seg.names {
+		params[name] = m[seg.indexes[i]]
+	}
+	return true
+}
@@ -0,0 +1,334 @@
+package gee
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+)
+
+// ServerConfig 用于配置Engine启动的http.Server以及关闭时的行为。
+type ServerConfig struct {
+	ReadTimeout       time.Duration // 读取整个请求（包括请求体）的超时时间
+	ReadHeaderTimeout time.Duration // 读取请求头的超时时间
+	WriteTimeout      time.Duration // 写出响应的超时时间
+	IdleTimeout       time.Duration // keep-alive连接的空闲超时时间
+	MaxHeaderBytes    int           // 请求头的最大字节数，为0时使用http包的默认值
+	// HandleSignals 为true时，收到SIGINT或SIGTERM后开始优雅关闭。
+	HandleSignals bool
+	// DrainDelay 开始关闭后先将就绪状态置为false并等待这段时间，
+	// 让负载均衡器摘除流量后再停止接受新连接。
+	DrainDelay time.Duration
+	// ShutdownTimeout 由ctx取消或信号触发的关闭最多等待的时间，为0时一直等待请求处理完毕。
+	ShutdownTimeout time.Duration
+	// ConfigureServer 在服务器启动前对http.Server做进一步的自定义，可以为nil。
+	ConfigureServer func(*http.Server)
+}
+
+// ShutdownHook 是引擎关闭时执行的清理函数，例如关闭LGRPC客户端或数据库连接。
+type ShutdownHook func(ctx context.Context) error
+
+// lifecycle 保存引擎运行中的服务器和关闭状态。
+type lifecycle struct {
+	mu       sync.Mutex
+	servers  []*http.Server
+	hooks    []ShutdownHook
+	draining atomic.Bool
+	once     sync.Once
+	err      error
+}
+
+// newServer 按照ServerConfig创建一个由engine处理请求的http.Server。
+func (engine *Engine) newServer(addr string) *http.Server {
+	conf := engine.root().ServerConfig
+	srv := &http.Server{
+		Addr:              addr,
+		Handler:           engine,
+		ReadTimeout:       conf.ReadTimeout,
+		ReadHeaderTimeout: conf.ReadHeaderTimeout,
+		WriteTimeout:      conf.WriteTimeout,
+		IdleTimeout:       conf.IdleTimeout,
+		MaxHeaderBytes:    conf.MaxHeaderBytes,
+	}
+	if conf.ConfigureServer != nil {
+		conf.ConfigureServer(srv)
+	}
+	return srv
+}
+
+// trackServer 记录正在运行的服务器，关闭时统一处理。
+// 如果引擎已经开始关闭，返回false。
+func (engine *Engine) trackServer(srv *http.Server) bool {
+	engine.life.mu.Lock()
+	defer engine.life.mu.Unlock()
+	if engine.life.draining.Load() {
+		return false
+	}
+	engine.life.servers = append(engine.life.servers, srv)
+	return true
+}
+
+// OnShutdown 注册一个在所有服务器停止后执行的关闭钩子。
+// 钩子按注册的相反顺序执行，后注册的资源先被释放。
+func (engine *Engine) OnShutdown(hook ShutdownHook) {
+	engine.life.mu.Lock()
+	defer engine.life.mu.Unlock()
+	engine.life.hooks = append(engine.life.hooks, hook)
+}
+
+// Ready 报告引擎是否处于可以接收流量的状态，开始关闭后返回false。
+func (engine *Engine) Ready() bool {
+	return !engine.life.draining.Load()
+}
+
+// Shutdown 优雅地关闭引擎：先将就绪状态置为false并等待DrainDelay，
+// 然后停止接受新连接并等待正在处理的请求完成，最后执行关闭钩子。
+// 多次调用只会执行一次关闭流程，之后的调用返回第一次的结果。
+func (engine *Engine) Shutdown(ctx context.Context) error {
+	engine.life.once.Do(func() {
+		engine.life.err = engine.shutdown(ctx)
+	})
+	return engine.life.err
+}
+
+func (engine *Engine) shutdown(ctx context.Context) error {
+	engine.life.mu.Lock()
+	engine.life.draining.Store(true)
+	servers := engine.life.servers
+	hooks := engine.life.hooks
+	engine.life.mu.Unlock()
+
+	if delay := engine.root().ServerConfig.DrainDelay; delay > 0 {
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+		}
+	}
+
+	var errs []error
+	var wg sync.WaitGroup
+	var errMu sync.Mutex
+	for _, srv := range servers {
+		wg.Add(1)
+		go func(srv *http.Server) {
+			defer wg.Done()
+			if err := srv.Shutdown(ctx); err != nil {
+				errMu.Lock()
+				errs = append(errs, err)
+				errMu.Unlock()
+			}
+		}(srv)
+	}
+	wg.Wait()
+
+	for i := len(hooks) - 1; i >= 0; i-- {
+		if err := hooks[i](ctx); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Listen 描述引擎的一个监听地址，RunListeners可以同时在多个地址上提供服务。
+type Listen struct {
+	Network   string       // 网络类型，"tcp"（默认）或"unix"
+	Addr      string       // 监听地址，unix时为socket文件路径
+	CertFile  string       // 证书文件，与KeyFile同时设置时以HTTPS提供服务
+	KeyFile   string       // 私钥文件
+	TLSConfig *tls.Config  // 自定义TLS配置，不为nil时以HTTPS提供服务，可用于开启mTLS
+	Listener  net.Listener // 已经创建好的监听器，设置后忽略Network和Addr
+	// RedirectHTTPS 非空时该地址不处理请求，而是把所有请求永久重定向到HTTPS，
+	// 值为HTTPS服务的端口，例如"443"或"8443"。
+	RedirectHTTPS string
+}
+
+// runner 是一个已经打开监听、等待启动的服务器。
+type runner struct {
+	srv  *http.Server
+	l    net.Listener
+	tls  bool
+	cert string
+	key  string
+	desc string
+}
+
+// open 创建Listen对应的监听器和服务器。
+func (engine *Engine) open(ln Listen) (*runner, error) {
+	l := ln.Listener
+	if l == nil {
+		network := ln.Network
+		if network == "" {
+			network = "tcp"
+		}
+		if network == "unix" {
+			if err := removeStaleSocket(ln.Addr); err != nil {
+				return nil, err
+			}
+		}
+		var err error
+		if l, err = net.Listen(network, ln.Addr); err != nil {
+			return nil, err
+		}
+	}
+	r := &runner{srv: engine.newServer(l.Addr().String()), l: l, desc: "HTTP"}
+	switch {
+	case ln.RedirectHTTPS != "":
+		r.srv.Handler = redirectHTTPS(ln.RedirectHTTPS)
+		r.desc = "HTTPS redirect"
+	case ln.TLSConfig != nil || (ln.CertFile != "" && ln.KeyFile != ""):
+		if ln.TLSConfig != nil {
+			r.srv.TLSConfig = ln.TLSConfig.Clone()
+		}
+		r.tls, r.cert, r.key, r.desc = true, ln.CertFile, ln.KeyFile, "HTTPS"
+	}
+	return r, nil
+}
+
+// removeStaleSocket 清理上一次运行遗留的socket文件。只有路径是socket且无法连接时才删除，
+// 仍有进程在监听时返回错误；路径是普通文件等其他类型时保持不动，交给net.Listen报错。
+func removeStaleSocket(file string) error {
+	info, err := os.Stat(file)
+	if err != nil || info.Mode()&os.ModeSocket == 0 {
+		return nil
+	}
+	conn, err := net.DialTimeout("unix", file, time.Second)
+	if err == nil {
+		conn.Close()
+		return fmt.Errorf("gee: unix socket %s is already in use", file)
+	}
+	return os.Remove(file)
+}
+
+// redirectHTTPS 返回把请求永久重定向到HTTPS的处理器，port为HTTPS端口。
+func redirectHTTPS(port string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		host := req.Host
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+		host = strings.Trim(host, "[]")
+		if port != "443" {
+			host = net.JoinHostPort(host, port)
+		} else if strings.Contains(host, ":") {
+			host = "[" + host + "]"
+		}
+		http.Redirect(w, req, "https://"+host+req.URL.RequestURI(), http.StatusPermanentRedirect)
+	})
+}
+
+// RunContext 启动服务器并阻塞，直到ctx被取消（或开启HandleSignals时收到信号）后完成优雅关闭。
+// 服务器正常关闭时返回nil。
+func (engine *Engine) RunContext(ctx context.Context, addr string) error {
+	return engine.RunListeners(ctx, Listen{Addr: addr})
+}
+
+// RunTLS 以HTTPS方式启动服务器，阻塞直到服务器关闭。
+func (engine *Engine) RunTLS(addr, certFile, keyFile string) error {
+	return engine.RunListeners(context.Background(), Listen{Addr: addr, CertFile: certFile, KeyFile: keyFile})
+}
+
+// RunMutualTLS 以双向TLS方式启动服务器，客户端必须提供由clientCAFile中的CA签发的证书。
+func (engine *Engine) RunMutualTLS(addr, certFile, keyFile, clientCAFile string) error {
+	conf, err := MutualTLSConfig(clientCAFile)
+	if err != nil {
+		return err
+	}
+	return engine.RunListeners(context.Background(), Listen{Addr: addr, CertFile: certFile, KeyFile: keyFile, TLSConfig: conf})
+}
+
+// RunListener 在已经创建好的监听器上提供服务，阻塞直到服务器关闭。
+func (engine *Engine) RunListener(l net.Listener) error {
+	return engine.RunListeners(context.Background(), Listen{Listener: l})
+}
+
+// RunUnix 在unix socket上提供服务，阻塞直到服务器关闭。
+func (engine *Engine) RunUnix(file string) error {
+	return engine.RunListeners(context.Background(), Listen{Network: "unix", Addr: file})
+}
+
+// RunListeners 同时在多个地址上提供服务并阻塞。
+// 任意一个服务器异常退出，或ctx被取消（开启HandleSignals时也包括收到信号）时，
+// 引擎会优雅关闭所有服务器。正常关闭时返回nil。
+func (engine *Engine) RunListeners(ctx context.Context, listens ...Listen) error {
+	if len(listens) == 0 {
+		return errors.New("gee: no listen address given")
+	}
+	runners := make([]*runner, 0, len(listens))
+	closeAll := func() {
+		for _, r := range runners {
+			r.l.Close()
+		}
+	}
+	for _, ln := range listens {
+		r, err := engine.open(ln)
+		if err != nil {
+			closeAll()
+			return err
+		}
+		runners = append(runners, r)
+	}
+	for _, r := range runners {
+		if !engine.trackServer(r.srv) {
+			closeAll()
+			return http.ErrServerClosed
+		}
+	}
+	engine.debugWarnings()
+	if engine.root().ServerConfig.HandleSignals {
+		var stop context.CancelFunc
+		ctx, stop = signal.NotifyContext(ctx, syscall.SIGINT, syscall.SIGTERM)
+		defer stop()
+	}
+
+	errCh := make(chan error, len(runners))
+	for _, r := range runners {
+		go func(r *runner) {
+			infoPrintf("Listening and serving %s on %s", r.desc, r.l.Addr())
+			if r.tls {
+				errCh <- r.srv.ServeTLS(r.l, r.cert, r.key)
+				return
+			}
+			errCh <- r.srv.Serve(r.l)
+		}(r)
+	}
+
+	var firstErr error
+	pending := len(runners)
+	select {
+	case err := <-errCh:
+		// 某个服务器先退出：可能是启动失败，也可能是其他地方调用了Shutdown
+		pending--
+		if !errors.Is(err, http.ErrServerClosed) {
+			firstErr = err
+		}
+	case <-ctx.Done():
+		infoPrintf("Shutting down server")
+	}
+	shutdownCtx := context.Background()
+	if timeout := engine.root().ServerConfig.ShutdownTimeout; timeout > 0 {
+		var cancel context.CancelFunc
+		shutdownCtx, cancel = context.WithTimeout(shutdownCtx, timeout)
+		defer cancel()
+	}
+	err := engine.Shutdown(shutdownCtx)
+	for ; pending > 0; pending-- {
+		if serveErr := <-errCh; firstErr == nil && !errors.Is(serveErr, http.ErrServerClosed) {
+			firstErr = serveErr
+		}
+	}
+	if firstErr != nil {
+		return firstErr
+	}
+	return err
+}
@@ -0,0 +1,367 @@
+package gee
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// listenLocal 在本机随机端口上创建监听器。
+func listenLocal(t *testing.T) net.Listener {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return l
+}
+
+// serve 在后台运行RunListeners，返回接收其结果的通道。
+func serve(engine *Engine, ctx context.Context, listens ...Listen) <-chan error {
+	errc := make(chan error, 1)
+	go func() { errc <- engine.RunListeners(ctx, listens...) }()
+	return errc
+}
+
+func TestGracefulShutdown(t *testing.T) {
+	r := New()
+	r.ServerConfig.DrainDelay = 20 * time.Millisecond
+	var order []string
+	r.OnShutdown(func(ctx context.Context) error { order = append(order, "first"); return nil })
+	r.OnShutdown(func(ctx context.Context) error { order = append(order, "second"); return nil })
+	started := make(chan struct{})
+	r.GET("/slow", func(c *Context) {
+		close(started)
+		time.Sleep(100 * time.Millisecond)
+		c.String(http.StatusOK, "done")
+	})
+
+	l := listenLocal(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	errc := serve(r, ctx, Listen{Listener: l})
+	body := make(chan string, 1)
+	go func() {
+		resp, err := http.Get("http://" + l.Addr().String() + "/slow")
+		if err != nil {
+			body <- err.Error()
+			return
+		}
+		defer resp.Body.Close()
+		b, _ := io.ReadAll(resp.Body)
+		body <- string(b)
+	}()
+	<-started
+	if !r.Ready() {
+		t.Fatal("engine not ready while serving")
+	}
+	cancel()
+	time.Sleep(10 * time.Millisecond)
+	if r.Ready() {
+		t.Fatal("engine still ready while draining")
+	}
+	if got := <-body; got != "done" {
+		t.Fatalf("in-flight request got %q", got)
+	}
+	if err := <-errc; err != nil {
+		t.Fatalf("RunListeners = %v", err)
+	}
+	if len(order) != 2 || order[0] != "second" || order[1] != "first" {
+		t.Fatalf("hooks ran in order %v, want reverse registration order", order)
+	}
+}
+
+func TestShutdownOnce(t *testing.T) {
+	r := New()
+	calls := 0
+	hookErr := errors.New("close db")
+	r.OnShutdown(func(ctx context.Context) error { calls++; return hookErr })
+
+	if err := r.Shutdown(context.Background()); !errors.Is(err, hookErr) {
+		t.Fatalf("Shutdown = %v", err)
+	}
+	if err := r.Shutdown(context.Background()); !errors.Is(err, hookErr) || calls != 1 {
+		t.Fatalf("second Shutdown = %v, hook calls = %d", err, calls)
+	}
+	if err := r.RunListener(listenLocal(t)); err != http.ErrServerClosed {
+		t.Fatalf("run after shutdown = %v, want http.ErrServerClosed", err)
+	}
+}
+
+func TestRunListenersErrors(t *testing.T) {
+	r := New()
+	if err := r.RunListeners(context.Background()); err == nil {
+		t.Fatal("no listen address accepted")
+	}
+	l := listenLocal(t)
+	defer l.Close()
+	if err := r.RunListeners(context.Background(), Listen{Addr: l.Addr().String()}); err == nil {
+		t.Fatal("listening on a used address succeeded")
+	}
+}
+
+func TestServerConfig(t *testing.T) {
+	r := New()
+	r.ServerConfig = ServerConfig{
+		ReadHeaderTimeout: time.Second,
+		ConfigureServer:   func(srv *http.Server) { srv.IdleTimeout = time.Minute },
+	}
+	srv := r.newServer(":0")
+	if srv.ReadHeaderTimeout != time.Second || srv.IdleTimeout != time.Minute || srv.Handler != r {
+		t.Fatalf("server = %+v", srv)
+	}
+}
+
+// testCA 是测试用的自签名CA，在进程内生成证书，不依赖外部文件。
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pool *x509.CertPool
+}
+
+func newTestCA(t *testing.T) *testCA {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "gee test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pool := x509.NewCertPool()
+	pool.AddCert(cert)
+	return &testCA{cert: cert, key: key, pool: pool}
+}
+
+// issue 签发同时可用于服务端和客户端认证的叶子证书，对127.0.0.1有效。
+func (ca *testCA) issue(t *testing.T, cn string) tls.Certificate {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: cn},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1)},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, ca.cert, &key.PublicKey, ca.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+}
+
+// writePEM 把证书和私钥写入临时目录，返回证书和私钥文件路径。
+func writePEM(t *testing.T, cert tls.Certificate) (certFile, keyFile string) {
+	t.Helper()
+	dir := t.TempDir()
+	keyDER, err := x509.MarshalPKCS8PrivateKey(cert.PrivateKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certFile, keyFile = filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Certificate[0]})
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER})
+	if err := os.WriteFile(certFile, certPEM, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(keyFile, keyPEM, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return certFile, keyFile
+}
+
+// freeAddr 返回本机当前空闲的地址，供只接受地址参数的RunTLS等方法使用。
+func freeAddr(t *testing.T) string {
+	l := listenLocal(t)
+	defer l.Close()
+	return l.Addr().String()
+}
+
+// waitDial 等待地址开始接受连接。
+func waitDial(t *testing.T, network, addr string) {
+	t.Helper()
+	for i := 0; i < 100; i++ {
+		if conn, err := net.Dial(network, addr); err == nil {
+			conn.Close()
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("%s %s is not accepting connections", network, addr)
+}
+
+// runBlocking 在后台运行run，测试结束时关闭引擎并检查run的返回值。
+func runBlocking(t *testing.T, r *Engine, run func() error) {
+	errc := make(chan error, 1)
+	go func() { errc <- run() }()
+	t.Cleanup(func() {
+		r.Shutdown(context.Background())
+		if err := <-errc; err != nil {
+			t.Errorf("server returned %v", err)
+		}
+	})
+}
+
+func TestRunTLS(t *testing.T) {
+	ca := newTestCA(t)
+	certFile, keyFile := writePEM(t, ca.issue(t, "server"))
+	r := New()
+	r.GET("/", func(c *Context) { c.String(http.StatusOK, "tls=%v", c.Req.TLS != nil) })
+	addr := freeAddr(t)
+	runBlocking(t, r, func() error { return r.RunTLS(addr, certFile, keyFile) })
+	waitDial(t, "tcp", addr)
+
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: ca.pool}}}
+	resp, err := client.Get("https://" + addr + "/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if b, _ := io.ReadAll(resp.Body); string(b) != "tls=true" {
+		t.Fatalf("body = %q", b)
+	}
+	if _, err := http.Get("https://" + addr + "/"); err == nil {
+		t.Fatal("client without the CA accepted the certificate")
+	}
+}
+
+func TestRunMutualTLS(t *testing.T) {
+	ca := newTestCA(t)
+	certFile, keyFile := writePEM(t, ca.issue(t, "server"))
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	if err := os.WriteFile(caFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ca.cert.Raw}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	r := New()
+	r.GET("/whoami", func(c *Context) { c.String(http.StatusOK, "%s", c.PeerIdentity()) })
+	addr := freeAddr(t)
+	runBlocking(t, r, func() error { return r.RunMutualTLS(addr, certFile, keyFile, caFile) })
+	waitDial(t, "tcp", addr)
+
+	clientFor := func(certs ...tls.Certificate) *http.Client {
+		return &http.Client{Transport: &http.Transport{
+			TLSClientConfig: &tls.Config{RootCAs: ca.pool, Certificates: certs},
+		}}
+	}
+	resp, err := clientFor(ca.issue(t, "alice")).Get("https://" + addr + "/whoami")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if b, _ := io.ReadAll(resp.Body); string(b) != "alice" {
+		t.Fatalf("PeerIdentity = %q, want alice", b)
+	}
+
+	if _, err := clientFor().Get("https://" + addr + "/whoami"); err == nil {
+		t.Fatal("client without a certificate was accepted")
+	}
+	other := newTestCA(t)
+	if _, err := clientFor(other.issue(t, "mallory")).Get("https://" + addr + "/whoami"); err == nil {
+		t.Fatal("client certificate from an unknown CA was accepted")
+	}
+}
+
+func TestRedirectHTTPS(t *testing.T) {
+	r := New()
+	l := listenLocal(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	errc := serve(r, ctx, Listen{Listener: l, RedirectHTTPS: "8443"})
+	defer func() {
+		cancel()
+		<-errc
+	}()
+
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+	resp, err := client.Get("http://" + l.Addr().String() + "/a/b?x=1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusPermanentRedirect || resp.Header.Get("Location") != "https://127.0.0.1:8443/a/b?x=1" {
+		t.Fatalf("got %d %q", resp.StatusCode, resp.Header.Get("Location"))
+	}
+	w := performRequest(redirectHTTPS("443"), http.MethodGet, "http://example.com:80/p", nil)
+	if w.Header().Get("Location") != "https://example.com/p" {
+		t.Fatalf("Location = %q", w.Header().Get("Location"))
+	}
+}
+
+func TestRunUnixSocket(t *testing.T) {
+	sock := filepath.Join(t.TempDir(), "gee.sock")
+	// 上一次运行遗留的socket文件
+	stale, err := net.Listen("unix", sock)
+	if err != nil {
+		t.Skip("unix sockets are not supported:", err)
+	}
+	stale.(*net.UnixListener).SetUnlinkOnClose(false)
+	stale.Close()
+
+	r := New()
+	r.GET("/", func(c *Context) { c.String(http.StatusOK, "unix") })
+	runBlocking(t, r, func() error { return r.RunUnix(sock) })
+	waitDial(t, "unix", sock)
+
+	client := &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, "unix", sock)
+		},
+	}}
+	resp, err := client.Get("http://gee/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if b, _ := io.ReadAll(resp.Body); string(b) != "unix" {
+		t.Fatalf("body = %q", b)
+	}
+
+	// 正在使用的socket不能被第二个引擎删除
+	if err := New().RunUnix(sock); err == nil || !strings.Contains(err.Error(), "already in use") {
+		t.Fatalf("second RunUnix = %v, want address in use", err)
+	}
+	// 普通文件不会被当作socket删除
+	file := filepath.Join(t.TempDir(), "not-a-socket")
+	if err := os.WriteFile(file, []byte("data"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := New().RunUnix(file); err == nil {
+		t.Fatal("RunUnix replaced a regular file")
+	}
+	if _, err := os.Stat(file); err != nil {
+		t.Fatalf("regular file was removed: %v", err)
+	}
+}
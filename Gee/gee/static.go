@@ -0,0 +1,279 @@
+package gee
+
+import (
+	"fmt"
+	"io/fs"
+	"mime"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// StaticConfig 用于配置静态文件服务的行为。
+type StaticConfig struct {
+	// Browse 为true时允许列出目录内容，默认关闭。
+	Browse bool
+	// Index 请求目录时返回的默认文件，为空时使用index.html。
+	Index string
+	// ETag 为true时根据文件大小和修改时间生成ETag。
+	ETag bool
+	// DisableLastModified 为true时不发送Last-Modified头。
+	DisableLastModified bool
+	// MaxAge 大于0时发送 "Cache-Control: public, max-age=N"。
+	MaxAge time.Duration
+	// Immutable 为true时在Cache-Control中追加immutable，适用于带哈希的文件名。
+	Immutable bool
+	// Precompressed 为true时，若客户端支持且存在同名的.br或.gz文件，则直接返回预压缩文件。
+	Precompressed bool
+	// SPA 为true时，未找到的路径返回Index文件，交给前端路由处理。
+	SPA bool
+}
+
+// onlyFilesFS 包装http.FileSystem，禁止列出目录内容。
+type onlyFilesFS struct {
+	fs http.FileSystem
+}
+
+// neuteredDir 是不支持Readdir的目录文件。
+type neuteredDir struct {
+	http.File
+}
+
+// Open 打开文件，目录的Readdir会被禁用。
+func (o onlyFilesFS) Open(name string) (http.File, error) {
+	f, err := o.fs.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	return neuteredDir{f}, nil
+}
+
+// Readdir 始终返回空列表，使目录内容不可被列出。
+func (f neuteredDir) Readdir(count int) ([]os.FileInfo, error) {
+	return nil, nil
+}
+
+// Dir 返回以root为根目录的http.FileSystem。
+// listDirectory为false时，目录内容不会被列出。
+func Dir(root string, listDirectory bool) http.FileSystem {
+	fs := http.Dir(root)
+	if listDirectory {
+		return fs
+	}
+	return onlyFilesFS{fs}
+}
+
+// createStaticHandler 创建一个处理静态文件请求的Handler。
+// relativePath 相对于路由组前缀的相对路径。
+// fs 是实现http.FileSystem接口的文件系统。
+// 返回一个Handlerfunc，用于处理静态文件请求。
+func (group *RouteGroup) createStaticHandler(relativePath string, fs http.FileSystem, conf StaticConfig) Handlerfunc {
+	// 将路由组前缀和相对路径合并，得到服务静态文件的绝对路径。
+	absolutePath := path.Join(group.prefix, relativePath)
+	// 目录列表仍交给http.FileServer生成。
+	fileServer := http.StripPrefix(absolutePath, http.FileServer(fs))
+	index := conf.Index
+	if index == "" {
+		index = "index.html"
+	}
+	return func(c *Context) {
+		// 从请求中获取文件路径参数，补全前导斜杠并清理路径中的 ".."。
+		name := path.Clean("/" + c.Param("filepath"))
+		f, stat, err := openStatic(fs, name)
+		if err == nil && stat.IsDir() {
+			indexName := path.Join(name, index)
+			if idx, idxStat, idxErr := openStatic(fs, indexName); idxErr == nil && !idxStat.IsDir() {
+				f.Close()
+				f, stat, name = idx, idxStat, indexName
+			} else if conf.Browse {
+				f.Close()
+				fileServer.ServeHTTP(c.Writer, c.Req)
+				return
+			} else {
+				f.Close()
+				err = os.ErrNotExist
+			}
+		}
+		if err != nil && conf.SPA {
+			// SPA模式下未知路径统一返回入口文件
+			name = "/" + index
+			f, stat, err = openStatic(fs, name)
+		}
+		if err != nil {
+			c.Status(http.StatusNotFound)
+			return
+		}
+		defer f.Close()
+		serveStaticFile(c, fs, f, stat, name, conf)
+	}
+}
+
+// openStatic 打开文件并获取其信息，失败时保证文件已关闭。
+func openStatic(fs http.FileSystem, name string) (http.File, os.FileInfo, error) {
+	f, err := fs.Open(name)
+	if err != nil {
+		return nil, nil, err
+	}
+	stat, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, nil, err
+	}
+	return f, stat, nil
+}
+
+// serveStaticFile 写出缓存相关的响应头，并通过http.ServeContent返回文件内容。
+// ServeContent会处理Range、If-None-Match和If-Modified-Since等条件请求。
+func serveStaticFile(c *Context, fs http.FileSystem, f http.File, stat os.FileInfo, name string, conf StaticConfig) {
+	header := c.Writer.Header()
+	content, size, modTime := http.File(f), stat.Size(), stat.ModTime()
+	if conf.Precompressed {
+		header.Add("Vary", "Accept-Encoding")
+		if enc, cf, cstat := openPrecompressed(c.Req, fs, name); cf != nil {
+			defer cf.Close()
+			content, size = cf, cstat.Size()
+			header.Set("Content-Encoding", enc)
+			// 以原始文件的扩展名确定Content-Type，避免被识别为压缩包
+			ctype := mime.TypeByExtension(path.Ext(name))
+			if ctype == "" {
+				ctype = "application/octet-stream"
+			}
+			header.Set("Content-Type", ctype)
+		}
+	}
+	if conf.ETag {
+		etag := fmt.Sprintf(`"%x-%x"`, modTime.UnixNano(), size)
+		if enc := header.Get("Content-Encoding"); enc != "" {
+			etag = etag[:len(etag)-1] + "-" + enc + `"`
+		}
+		header.Set("ETag", etag)
+	}
+	if conf.MaxAge > 0 {
+		cache := "public, max-age=" + strconv.FormatInt(int64(conf.MaxAge/time.Second), 10)
+		if conf.Immutable {
+			cache += ", immutable"
+		}
+		header.Set("Cache-Control", cache)
+	}
+	if conf.DisableLastModified {
+		modTime = time.Time{}
+	}
+	http.ServeContent(c.Writer, c.Req, name, modTime, content)
+}
+
+// precompressedEncodings 按优先级列出支持的预压缩格式及其文件后缀。
+var precompressedEncodings = []struct{ encoding, ext string }{
+	{"br", ".br"},
+	{"gzip", ".gz"},
+}
+
+// openPrecompressed 根据Accept-Encoding查找可用的预压缩文件。
+func openPrecompressed(req *http.Request, fs http.FileSystem, name string) (string, http.File, os.FileInfo) {
+	accept := req.Header.Get("Accept-Encoding")
+	if accept == "" || req.Header.Get("Range") != "" {
+		return "", nil, nil
+	}
+	for _, pe := range precompressedEncodings {
+		if !acceptsEncoding(accept, pe.encoding) {
+			continue
+		}
+		if f, stat, err := openStatic(fs, name+pe.ext); err == nil {
+			if !stat.IsDir() {
+				return pe.encoding, f, stat
+			}
+			f.Close()
+		}
+	}
+	return "", nil, nil
+}
+
+// acceptsEncoding 判断Accept-Encoding是否接受某种编码（忽略q=0的项）。
+func acceptsEncoding(accept, encoding string) bool {
+	for _, item := range strings.Split(accept, ",") {
+		parts := strings.Split(strings.TrimSpace(item), ";")
+		if !strings.EqualFold(strings.TrimSpace(parts[0]), encoding) {
+			continue
+		}
+		for _, p := range parts[1:] {
+			if q := strings.TrimSpace(p); q == "q=0" || q == "q=0.0" || q == "q=0.00" || q == "q=0.000" {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// Static 注册一个处理静态文件请求的路由。
+// relativePath 是相对于应用根路径的静态资源路径。
+// root 是静态资源在文件系统中的根目录，默认不列出目录内容。
+func (group *RouteGroup) Static(relativePath string, root string) {
+	group.StaticFS(relativePath, Dir(root, false))
+}
+
+// StaticFS 与Static相同，但可以传入自定义的http.FileSystem。
+func (group *RouteGroup) StaticFS(relativePath string, fs http.FileSystem) {
+	group.StaticWithConfig(relativePath, fs, StaticConfig{})
+}
+
+// StaticEmbed 使用fs.FS（例如embed.FS）提供静态文件，root为其中作为根目录的子目录。
+func (group *RouteGroup) StaticEmbed(relativePath string, fsys fs.FS, root string) {
+	group.StaticWithConfig(relativePath, EmbedFS(fsys, root), StaticConfig{})
+}
+
+// EmbedFS 将fs.FS中的root子目录转换为http.FileSystem，root不存在时panic。
+func EmbedFS(fsys fs.FS, root string) http.FileSystem {
+	if root != "" && root != "." {
+		sub, err := fs.Sub(fsys, root)
+		if err != nil {
+			panic(err)
+		}
+		fsys = sub
+	}
+	return http.FS(fsys)
+}
+
+// StaticWithConfig 按照给定配置注册静态文件路由。
+func (group *RouteGroup) StaticWithConfig(relativePath string, fs http.FileSystem, conf StaticConfig) {
+	if strings.Contains(relativePath, ":") || strings.Contains(relativePath, "*") {
+		panic("URL parameters can not be used when serving a static folder")
+	}
+	// 创建处理静态文件请求的handler。
+	handler := group.createStaticHandler(relativePath, fs, conf)
+	// 构建匹配静态文件请求的URL模式。
+	urlPattern := path.Join(relativePath, "/*filepath")
+	// 使用GET和HEAD方法注册处理静态文件请求的路由。
+	group.GET(urlPattern, handler)
+	group.addRoute(http.MethodHead, urlPattern, handler)
+}
+
+// StaticFile 注册一个返回单个本地文件的路由。
+func (group *RouteGroup) StaticFile(relativePath, file string) {
+	group.StaticFileFS(relativePath, filepath.Base(file), Dir(filepath.Dir(file), false))
+}
+
+// StaticFileFS 注册一个返回文件系统中单个文件的路由。
+func (group *RouteGroup) StaticFileFS(relativePath, file string, fs http.FileSystem) {
+	if strings.Contains(relativePath, ":") || strings.Contains(relativePath, "*") {
+		panic("URL parameters can not be used when serving a static file")
+	}
+	name := path.Clean("/" + file)
+	handler := func(c *Context) {
+		f, stat, err := openStatic(fs, name)
+		if err != nil || stat.IsDir() {
+			if f != nil {
+				f.Close()
+			}
+			c.Status(http.StatusNotFound)
+			return
+		}
+		defer f.Close()
+		serveStaticFile(c, fs, f, stat, name, StaticConfig{})
+	}
+	group.GET(relativePath, handler)
+	group.addRoute(http.MethodHead, relativePath, handler)
+}
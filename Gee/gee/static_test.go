@@ -0,0 +1,115 @@
+package gee
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+// writeFiles 在临时目录中创建文件，files的键为相对路径。
+func writeFiles(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		p := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestStatic(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"app.js":          "console.log(1)",
+		"docs/index.html": "<h1>docs</h1>",
+		"empty/.keep":     "",
+	})
+	r := New()
+	r.Static("/assets", dir)
+
+	tests := []struct {
+		path string
+		code int
+		body string
+	}{
+		{"/assets/app.js", http.StatusOK, "console.log(1)"},
+		{"/assets/docs/", http.StatusOK, "<h1>docs</h1>"},
+		{"/assets/empty/", http.StatusNotFound, ""},
+		{"/assets/missing.js", http.StatusNotFound, ""},
+		{"/assets/../static_test.go", http.StatusNotFound, ""},
+	}
+	for _, tt := range tests {
+		w := performRequest(r, http.MethodGet, tt.path, nil)
+		if w.Code != tt.code || (tt.body != "" && w.Body.String() != tt.body) {
+			t.Errorf("GET %s = %d %q, want %d %q", tt.path, w.Code, w.Body.String(), tt.code, tt.body)
+		}
+	}
+	if w := performRequest(r, http.MethodHead, "/assets/app.js", nil); w.Code != http.StatusOK || w.Body.Len() != 0 {
+		t.Errorf("HEAD = %d %q", w.Code, w.Body.String())
+	}
+}
+
+func TestStaticWithConfig(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"app.js":     "console.log(1)",
+		"app.js.gz":  "GZDATA",
+		"index.html": "<h1>spa</h1>",
+	})
+	r := New()
+	r.StaticWithConfig("/assets", Dir(dir, false), StaticConfig{
+		ETag: true, MaxAge: time.Hour, Immutable: true, Precompressed: true, SPA: true,
+	})
+
+	w := performRequest(r, http.MethodGet, "/assets/app.js", nil, "Accept-Encoding", "br, gzip")
+	if w.Body.String() != "GZDATA" || w.Header().Get("Content-Encoding") != "gzip" {
+		t.Fatalf("precompressed: %q %v", w.Body.String(), w.Header())
+	}
+	if !strings.HasPrefix(w.Header().Get("Content-Type"), "text/javascript") {
+		t.Fatalf("Content-Type = %q", w.Header().Get("Content-Type"))
+	}
+	if got := w.Header().Get("Cache-Control"); got != "public, max-age=3600, immutable" {
+		t.Fatalf("Cache-Control = %q", got)
+	}
+	etag := w.Header().Get("ETag")
+	w = performRequest(r, http.MethodGet, "/assets/app.js", nil, "Accept-Encoding", "gzip", "If-None-Match", etag)
+	if w.Code != http.StatusNotModified {
+		t.Fatalf("If-None-Match: status = %d", w.Code)
+	}
+	w = performRequest(r, http.MethodGet, "/assets/app.js", nil, "Accept-Encoding", "gzip;q=0")
+	if w.Body.String() != "console.log(1)" {
+		t.Fatalf("q=0: body = %q", w.Body.String())
+	}
+	w = performRequest(r, http.MethodGet, "/assets/users/42", nil)
+	if w.Code != http.StatusOK || w.Body.String() != "<h1>spa</h1>" {
+		t.Fatalf("SPA fallback: %d %q", w.Code, w.Body.String())
+	}
+}
+
+func TestStaticEmbedAndFile(t *testing.T) {
+	fsys := fstest.MapFS{"public/hello.txt": {Data: []byte("hello")}}
+	dir := writeFiles(t, map[string]string{"favicon.ico": "icon"})
+	r := New()
+	r.StaticEmbed("/e", fsys, "public")
+	r.StaticFile("/favicon.ico", filepath.Join(dir, "favicon.ico"))
+
+	if w := performRequest(r, http.MethodGet, "/e/hello.txt", nil); w.Body.String() != "hello" {
+		t.Fatalf("embed: %d %q", w.Code, w.Body.String())
+	}
+	if w := performRequest(r, http.MethodGet, "/favicon.ico", nil); w.Body.String() != "icon" {
+		t.Fatalf("file: %d %q", w.Code, w.Body.String())
+	}
+	defer func() {
+		if recover() == nil {
+			t.Fatal("static route with a parameter did not panic")
+		}
+	}()
+	r.Static("/:dir", dir)
+}
@@ -0,0 +1,152 @@
+package gee
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// SSEvent 是一条Server-Sent Events事件。
+type SSEvent struct {
+	Event string      // 事件名，为空时客户端按message事件处理
+	ID    string      // 事件ID，客户端重连时通过Last-Event-ID带回
+	Retry uint        // 建议客户端重连的间隔（毫秒），为0时不发送
+	Data  interface{} // 事件数据，string和[]byte原样发送，其他类型编码为JSON
+}
+
+// Stream 持续调用step向客户端写出数据，每次调用后立即flush。
+// step返回false或客户端断开连接时结束，返回值表示客户端是否中途断开。
+// 数据通过c.Writer写出，因此包装了Writer的压缩、日志等中间件同样生效。
+func (c *Context) Stream(step func(w io.Writer) bool) bool {
+	clientGone := c.Req.Context().Done()
+	w := c.Writer
+	for {
+		select {
+		case <-clientGone:
+			return true
+		default:
+			keepOpen := step(w)
+			w.Flush()
+			if !keepOpen {
+				return false
+			}
+		}
+	}
+}
+
+// SSEvent 以Server-Sent Events格式写出一条事件并立即flush。
+func (c *Context) SSEvent(name string, message interface{}) {
+	c.SSE(SSEvent{Event: name, Data: message})
+}
+
+// SSE 写出一条完整的事件并立即flush，第一次调用时写出text/event-stream响应头。
+func (c *Context) SSE(ev SSEvent) error {
+	c.sseHeaders()
+	if err := encodeSSE(c.Writer, ev); err != nil {
+		return err
+	}
+	c.Writer.Flush()
+	return nil
+}
+
+// SSEKeepAlive 写出一行SSE注释，用于防止代理因连接空闲而断开。
+func (c *Context) SSEKeepAlive() {
+	c.sseHeaders()
+	io.WriteString(c.Writer, ": keepalive\n\n")
+	c.Writer.Flush()
+}
+
+// SSEStream 把events中的事件依次推送给客户端，keepAlive大于0时在空闲期间定期发送注释。
+// events被关闭或客户端断开时返回，返回值表示客户端是否中途断开。
+func (c *Context) SSEStream(events <-chan SSEvent, keepAlive time.Duration) bool {
+	c.sseHeaders()
+	c.Writer.Flush()
+	var tick <-chan time.Time
+	if keepAlive > 0 {
+		ticker := time.NewTicker(keepAlive)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+	clientGone := c.Req.Context().Done()
+	for {
+		select {
+		case <-clientGone:
+			return true
+		case ev, ok := <-events:
+			if !ok {
+				return false
+			}
+			if err := c.SSE(ev); err != nil {
+				return true
+			}
+		case <-tick:
+			c.SSEKeepAlive()
+		}
+	}
+}
+
+// LastEventID 返回客户端重连时携带的最后一个事件ID，用于从断点继续推送。
+// 优先读取Last-Event-ID请求头，其次是lastEventId查询参数（部分polyfill使用）。
+func (c *Context) LastEventID() string {
+	if id := c.Req.Header.Get("Last-Event-ID"); id != "" {
+		return id
+	}
+	return c.Req.URL.Query().Get("lastEventId")
+}
+
+// sseHeaders 在响应头尚未写出时设置SSE所需的响应头。
+func (c *Context) sseHeaders() {
+	if c.Writer.Written() {
+		return
+	}
+	header := c.Writer.Header()
+	header.Set("Content-Type", "text/event-stream")
+	header.Set("Cache-Control", "no-cache")
+	header.Set("Connection", "keep-alive")
+	// 关闭nginx等反向代理的缓冲，保证事件及时到达客户端
+	header.Set("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+}
+
+// sseReplacer 去掉字段值中的换行，防止注入额外的字段。
+var sseReplacer = strings.NewReplacer("\n", "", "\r", "")
+
+// encodeSSE 按照SSE格式编码事件，多行数据会拆分为多个data字段。
+func encodeSSE(w io.Writer, ev SSEvent) error {
+	var b strings.Builder
+	if ev.ID != "" {
+		b.WriteString("id: " + sseReplacer.Replace(ev.ID) + "\n")
+	}
+	if ev.Event != "" {
+		b.WriteString("event: " + sseReplacer.Replace(ev.Event) + "\n")
+	}
+	if ev.Retry > 0 {
+		b.WriteString(fmt.Sprintf("retry: %d\n", ev.Retry))
+	}
+	var data string
+	switch v := ev.Data.(type) {
+	case nil:
+	case string:
+		data = v
+	case []byte:
+		data = string(v)
+	default:
+		buf, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		data = string(buf)
+	}
+	// SSE把CRLF、单独的CR和LF都视为换行，全部拆分为独立的data字段，防止数据中注入其他字段
+	data = strings.ReplaceAll(data, "\r\n", "\n")
+	data = strings.ReplaceAll(data, "\r", "\n")
+	for _, line := range strings.Split(data, "\n") {
+		b.WriteString("data: " + line + "\n")
+	}
+	b.WriteString("\n")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
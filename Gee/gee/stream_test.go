@@ -0,0 +1,108 @@
+package gee
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEncodeSSE(t *testing.T) {
+	tests := []struct {
+		name string
+		ev   SSEvent
+		want string
+	}{
+		{"fields", SSEvent{ID: "7", Event: "tick", Retry: 3000, Data: "x"},
+			"id: 7\nevent: tick\nretry: 3000\ndata: x\n\n"},
+		{"json", SSEvent{Data: H{"n": 1}}, "data: {\"n\":1}\n\n"},
+		{"multiline", SSEvent{Data: "a\nb\r\nc"}, "data: a\ndata: b\ndata: c\n\n"},
+		{"lone CR", SSEvent{Data: []byte("x\rid: evil")}, "data: x\ndata: id: evil\n\n"},
+		{"field injection", SSEvent{ID: "1\nevent: evil", Event: "a\rb"}, "id: 1event: evil\nevent: ab\ndata: \n\n"},
+	}
+	for _, tt := range tests {
+		var buf bytes.Buffer
+		if err := encodeSSE(&buf, tt.ev); err != nil {
+			t.Fatal(err)
+		}
+		if buf.String() != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.name, buf.String(), tt.want)
+		}
+	}
+}
+
+func TestSSEStream(t *testing.T) {
+	r := New()
+	r.GET("/events", func(c *Context) {
+		events := make(chan SSEvent)
+		go func() {
+			events <- SSEvent{ID: c.LastEventID() + "1", Data: "first"}
+			time.Sleep(50 * time.Millisecond)
+			events <- SSEvent{Event: "done"}
+			close(events)
+		}()
+		c.SSEStream(events, 10*time.Millisecond)
+	})
+	srv := httptest.NewServer(r)
+	defer srv.Close()
+
+	req, _ := http.NewRequest(http.MethodGet, srv.URL+"/events", nil)
+	req.Header.Set("Last-Event-ID", "4")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	b, _ := io.ReadAll(resp.Body)
+	body := string(b)
+	if resp.Header.Get("Content-Type") != "text/event-stream" || resp.Header.Get("Cache-Control") != "no-cache" {
+		t.Fatalf("headers = %v", resp.Header)
+	}
+	if !strings.HasPrefix(body, "id: 41\ndata: first\n\n") || !strings.Contains(body, ": keepalive\n\n") ||
+		!strings.HasSuffix(body, "event: done\ndata: \n\n") {
+		t.Fatalf("body = %q", body)
+	}
+}
+
+func TestStream(t *testing.T) {
+	r := New()
+	r.GET("/stream", func(c *Context) {
+		n := 0
+		gone := c.Stream(func(w io.Writer) bool {
+			n++
+			io.WriteString(w, "x")
+			return n < 3
+		})
+		if gone {
+			t.Error("Stream reported a disconnected client")
+		}
+	})
+	w := performRequest(r, http.MethodGet, "/stream", nil)
+	if w.Body.String() != "xxx" || !w.Flushed {
+		t.Fatalf("body = %q, flushed = %v", w.Body.String(), w.Flushed)
+	}
+}
+
+func TestResponseWriterStatus(t *testing.T) {
+	rec := httptest.NewRecorder()
+	w := &responseWriter{}
+	w.reset(rec)
+	if w.Written() || w.Status() != http.StatusOK {
+		t.Fatal("new writer reports a written response")
+	}
+	w.WriteHeader(http.StatusCreated)
+	w.WriteHeader(http.StatusAccepted)
+	w.Write([]byte("abc"))
+	if rec.Code != http.StatusCreated || w.Status() != http.StatusCreated || w.Size() != 3 || !w.Written() {
+		t.Fatalf("code = %d, status = %d, size = %d", rec.Code, w.Status(), w.Size())
+	}
+	if w.Unwrap() != rec {
+		t.Fatal("Unwrap did not return the underlying writer")
+	}
+	if _, _, err := w.Hijack(); err == nil {
+		t.Fatal("Hijack succeeded on a recorder")
+	}
+}
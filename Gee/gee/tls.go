@@ -0,0 +1,53 @@
+package gee
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"os"
+)
+
+// MutualTLSConfig 创建要求客户端证书的TLS配置，客户端证书必须由clientCAFile中的CA签发。
+func MutualTLSConfig(clientCAFile string) (*tls.Config, error) {
+	pem, err := os.ReadFile(clientCAFile)
+	if err != nil {
+		return nil, err
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, errors.New("gee: no certificates found in " + clientCAFile)
+	}
+	return &tls.Config{
+		ClientAuth: tls.RequireAndVerifyClientCert,
+		ClientCAs:  pool,
+		MinVersion: tls.VersionTLS12,
+	}, nil
+}
+
+// ClientCertificate 返回已经通过校验的客户端证书。
+// 请求不是TLS请求，或客户端证书未经校验时返回nil。
+func (c *Context) ClientCertificate() *x509.Certificate {
+	if c.Req.TLS == nil || len(c.Req.TLS.VerifiedChains) == 0 || len(c.Req.TLS.VerifiedChains[0]) == 0 {
+		return nil
+	}
+	return c.Req.TLS.VerifiedChains[0][0]
+}
+
+// PeerIdentity 返回已校验客户端证书的身份：优先使用Subject的CommonName，
+// 其次是第一个URI或DNS类型的SAN。没有已校验的证书时返回空字符串。
+func (c *Context) PeerIdentity() string {
+	cert := c.ClientCertificate()
+	if cert == nil {
+		return ""
+	}
+	if cert.Subject.CommonName != "" {
+		return cert.Subject.CommonName
+	}
+	if len(cert.URIs) > 0 {
+		return cert.URIs[0].String()
+	}
+	if len(cert.DNSNames) > 0 {
+		return cert.DNSNames[0]
+	}
+	return ""
+}
@@ -1,6 +1,10 @@
 package gee
 
-import "strings"
+import (
+	"regexp"
+	"sort"
+	"strings"
+)
 
 // 接下来我们实现的动态路由具备以下两个功能。
 // 参数匹配:。例如 /p/:lang/doc，可以匹配 /p/c/doc 和 /p/go/doc。
@@ -8,24 +12,126 @@ import "strings"
 // 也可以匹配/static/js/jQuery.js，这种模式常用于静态服务器，
 // 能够递归地匹配子路径。
 
+// 参数还可以带约束，写在参数名后的尖括号中，例如 /users/:id<int>、/posts/:slug<[a-z-]+>。
+// 约束在查找时检查，不满足约束的路径段会继续尝试其他路由，
+// 因此 /users/:id<int> 和 /users/me 可以同时注册。
+
+// paramConstraints 是内置的具名约束。
+var paramConstraints = map[string]string{
+	"int":   `-?[0-9]+`,
+	"uint":  `[0-9]+`,
+	"alpha": `[a-zA-Z]+`,
+	"alnum": `[a-zA-Z0-9]+`,
+	"hex":   `[0-9a-fA-F]+`,
+	"uuid":  `[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}`,
+}
+
+// splitParam 把 ":name<constraint>" 拆分为参数名和约束表达式，没有约束时约束为空。
+func splitParam(part string) (name string, constraint string) {
+	name = part[1:]
+	if i := strings.IndexByte(name, '<'); i >= 0 && strings.HasSuffix(name, ">") {
+		return name[:i], name[i+1 : len(name)-1]
+	}
+	return name, ""
+}
+
+// checkConstraints 检查路由模式中的参数约束是否包含"/"。约束只匹配一个路径段，
+// 包含"/"的约束（例如 :p<a/b>）会在拆分路径段时被截断，因此在注册时直接报错。
+func checkConstraints(pattern string) {
+	for i := 0; i < len(pattern); i++ {
+		if pattern[i] != ':' {
+			continue
+		}
+		j := i + 1
+		for j < len(pattern) && isParamNameChar(pattern[j]) {
+			j++
+		}
+		if j >= len(pattern) || pattern[j] != '<' {
+			continue
+		}
+		end := strings.IndexByte(pattern[j:], '>')
+		if end < 0 {
+			return // 未闭合的约束在编译路径段时报错
+		}
+		if strings.IndexByte(pattern[j:j+end], '/') >= 0 {
+			panic("gee: parameter constraint must not contain '/' in route '" + pattern + "', a constraint matches a single path segment")
+		}
+		i = j + end
+	}
+}
+
+// compileConstraint 把约束编译为完整匹配路径段的正则表达式，约束非法时panic。
+func compileConstraint(constraint string) *regexp.Regexp {
+	if expr, ok := paramConstraints[constraint]; ok {
+		constraint = expr
+	}
+	re, err := regexp.Compile("^(?:" + constraint + ")$")
+	if err != nil {
+		panic("gee: invalid route parameter constraint <" + constraint + ">: " + err.Error())
+	}
+	return re
+}
+
 // 实现前缀树路由
 // node 结构体表示树形结构的一个节点
 type node struct {
-	pattern  string  // pattern 表示节点匹配的模式字符串
-	part     string  // part 表示模式字符串中的一部分，即该节点代表的具体内容
-	children []*node // children 是该节点的子节点们，构成树的下一层级
-	isWild   bool    // isWild 表示该节点的模式字符串是否包含通配符，true 表示包含，false 表示不包含
+	pattern    string          // pattern 表示节点匹配的模式字符串
+	part       string          // part 表示模式字符串中的一部分，即该节点代表的具体内容
+	children   []*node         // children 是该节点的子节点们，构成树的下一层级
+	isWild     bool            // isWild 表示该节点的模式字符串是否包含通配符，true 表示包含，false 表示不包含
+	constraint *regexp.Regexp  // constraint 是参数节点的约束，为nil表示匹配任意路径段
+	segment    *segmentPattern // segment 是混合了字面量和参数的路径段，例如 ":name.:ext"
+}
+
+// priority 返回节点在查找时的优先级，数值越小越先尝试：
+// 静态路径段优先，其次是混合路径段和带约束的参数，然后是普通参数，最后是 * 通配符。
+func (n *node) priority() int {
+	switch {
+	case !n.isWild:
+		return 0
+	case n.segment != nil:
+		return 1
+	case n.constraint != nil:
+		return 2
+	case n.part[0] == ':':
+		return 3
+	}
+	return 4
+}
+
+// accepts 判断路径段是否满足该节点的匹配条件。
+func (n *node) accepts(part string) bool {
+	if !n.isWild {
+		return n.part == part
+	}
+	if n.segment != nil {
+		return n.segment.re.MatchString(part)
+	}
+	return n.constraint == nil || n.constraint.MatchString(part)
 }
 
 // 第一个匹配成功的节点，用于插入
 // matchChild 用于在节点的子节点中查找匹配的子节点。
+// 静态路径段和带约束的参数只与完全相同的part合并；
+// 不带约束的参数之间、形状相同的混合路径段之间可以合并，参数名以叶子节点的pattern为准。
 // part: 需要匹配的字符串。
 // 返回值: 如果找到匹配的子节点，则返回该子节点的指针；否则返回nil。
 func (n *node) matchChild(part string) *node {
 	for _, child := range n.children { // 遍历所有子节点
-		if child.part == part || child.isWild { // 如果子节点的part与目标字符串匹配，或者子节点是通配符节点，则返回该子节点
+		if child.part == part {
 			return child
 		}
+		if child.segment != nil {
+			if isCompositeSegment(part) && compileSegment(part).shape == child.segment.shape {
+				return child
+			}
+			continue
+		}
+		if child.isWild && child.constraint == nil && child.part[0] == part[0] && (part[0] == ':' || part[0] == '*') {
+			if _, constraint := splitParam(part); constraint == "" {
+				return child
+			}
+		}
 	}
 	// 遍历所有子节点后仍未找到匹配的子节点，返回nil
 	return nil
@@ -38,14 +144,20 @@ func (n *node) matchChild(part string) *node {
 func (n *node) matchChildren(part string) []*node {
 	nodes := make([]*node, 0) // 初始化一个空的节点切片，用于存放匹配结果
 
-	for _, child := range n.children { // 遍历所有子节点
-		if child.part == part || child.isWild { // 判断子节点是否与指定部分匹配或为通配符
+	for _, child := range n.children { // 遍历所有子节点，子节点已按优先级排序
+		if child.accepts(part) { // 判断子节点是否与指定部分匹配，或为满足约束的通配符
 			nodes = append(nodes, child) // 将匹配到的子节点添加到结果切片中
 		}
 	}
 	return nodes
 }
 
+// sameRoute 判断两个模式是否只在斜杠上不同，例如 /a 和 /a/，
+// 它们本来就对应同一个路由，后注册的覆盖先注册的，不算冲突。
+func sameRoute(a, b string) bool {
+	return strings.Join(parsePattern(a), "/") == strings.Join(parsePattern(b), "/")
+}
+
 // 插入
 // insert函数用于将给定的字符串模式插入到树中。
 // pattern: 需要插入的字符串模式。
@@ -53,6 +165,10 @@ func (n *node) matchChildren(part string) []*node {
 // height: 当前处理的部分在模式字符串中的层级高度。
 func (n *node) insert(pattern string, parts []string, height int) {
 	if len(parts) == height { // 当已经遍历完所有parts，即到达树的叶子节点时，设置当前节点的pattern为给定模式
+		if n.pattern != "" && n.pattern != pattern && !sameRoute(n.pattern, pattern) {
+			// 两个模式匹配的路径完全相同，后注册的路由永远不会被匹配到
+			panic("gee: route '" + pattern + "' conflicts with existing route '" + n.pattern + "'")
+		}
 		n.pattern = pattern
 		return
 	}
@@ -61,7 +177,19 @@ func (n *node) insert(pattern string, parts []string, height int) {
 	if child == nil {           // 如果没有匹配到子节点，创建一个新的子节点
 		child = &node{part: part,
 			isWild: part[0] == ':' || part[0] == '*'}
+		if isCompositeSegment(part) {
+			child.isWild = true
+			child.segment = compileSegment(part)
+		} else if part[0] == ':' {
+			if _, constraint := splitParam(part); constraint != "" {
+				child.constraint = compileConstraint(constraint)
+			}
+		}
 		n.children = append(n.children, child) // 将新子节点添加到当前节点的子节点列表中
+		// 按优先级排序，保证查找时静态路径段先于参数被尝试
+		sort.SliceStable(n.children, func(i, j int) bool {
+			return n.children[i].priority() < n.children[j].priority()
+		})
 	}
 	// 递归调用insert函数，继续在子节点中插入剩余的parts
 	child.insert(pattern, parts, height+1)
@@ -0,0 +1,756 @@
+package gee
+
+import (
+	"bufio"
+	"bytes"
+	"compress/flate"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// WebSocket消息类型，取值与RFC 6455中的操作码一致。
+const (
+	TextMessage   = 1
+	BinaryMessage = 2
+	CloseMessage  = 8
+	PingMessage   = 9
+	PongMessage   = 10
+
+	continuationFrame = 0
+)
+
+// WebSocket关闭码，见RFC 6455第7.4节和IANA的WebSocket Close Code Number Registry。
+const (
+	CloseNormalClosure           = 1000
+	CloseGoingAway               = 1001
+	CloseProtocolError           = 1002
+	CloseUnsupportedData         = 1003
+	CloseNoStatusReceived        = 1005
+	CloseAbnormalClosure         = 1006
+	CloseInvalidFramePayloadData = 1007
+	ClosePolicyViolation         = 1008
+	CloseMessageTooBig           = 1009
+	CloseMandatoryExtension      = 1010
+	CloseInternalServerErr       = 1011
+	CloseServiceRestart          = 1012
+	CloseTryAgainLater           = 1013
+	CloseBadGateway              = 1014
+)
+
+const (
+	// websocketGUID 是计算Sec-WebSocket-Accept时拼接的固定字符串。
+	websocketGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+	// maxControlPayload 是控制帧负载的最大长度。
+	maxControlPayload = 125
+	// defaultControlWait 是自动回复ping和close时的写超时。
+	defaultControlWait = time.Second
+	// DefaultWebSocketReadLimit 是WebSocketUpgrader.ReadLimit为0时单条消息的最大字节数。
+	DefaultWebSocketReadLimit = 32 << 20
+	// payloadChunkSize 是读取帧负载时每次分配的最大字节数，
+	// 内存随实际收到的数据增长，而不是按客户端声明的长度一次性分配。
+	payloadChunkSize = 64 << 10
+)
+
+var (
+	// ErrCloseSent 表示已经发送过关闭帧，连接上不能再写出数据消息。
+	ErrCloseSent = errors.New("websocket: close sent")
+	// ErrReadLimit 表示收到的消息超过了SetReadLimit设置的大小。
+	ErrReadLimit = errors.New("websocket: read limit exceeded")
+)
+
+// CloseError 表示收到了对端的关闭帧，或因协议错误而关闭了连接。
+type CloseError struct {
+	Code int
+	Text string
+}
+
+func (e *CloseError) Error() string {
+	return "websocket: close " + strconv.Itoa(e.Code) + " " + e.Text
+}
+
+// IsCloseError 判断err是否为指定关闭码之一的CloseError。
+func IsCloseError(err error, codes ...int) bool {
+	var ce *CloseError
+	if !errors.As(err, &ce) {
+		return false
+	}
+	for _, code := range codes {
+		if ce.Code == code {
+			return true
+		}
+	}
+	return false
+}
+
+// FormatCloseMessage 生成关闭帧的负载，code为CloseNoStatusReceived时返回空负载。
+func FormatCloseMessage(code int, text string) []byte {
+	if code == CloseNoStatusReceived {
+		return []byte{}
+	}
+	buf := make([]byte, 2+len(text))
+	binary.BigEndian.PutUint16(buf, uint16(code))
+	copy(buf[2:], text)
+	return buf
+}
+
+// WebSocketUpgrader 负责把HTTP请求升级为WebSocket连接。
+// 升级在普通的GET路由中完成，因此中间件链对握手请求同样生效。
+type WebSocketUpgrader struct {
+	// ReadBufferSize 和 WriteBufferSize 指定读写缓冲区的大小，为0时使用4096。
+	ReadBufferSize  int
+	WriteBufferSize int
+	// FragmentSize 大于0时，超过该长度的消息会拆分为多个帧发送。
+	FragmentSize int
+	// Subprotocols 服务端支持的子协议，按优先级排列。
+	Subprotocols []string
+	// CheckOrigin 校验请求的Origin，为nil时只允许同源请求或没有Origin的请求。
+	CheckOrigin func(r *http.Request) bool
+	// EnableCompression 为true时与客户端协商permessage-deflate扩展。
+	EnableCompression bool
+	// HandshakeTimeout 写出握手响应的超时时间，为0时不限制。
+	HandshakeTimeout time.Duration
+	// ReadLimit 单条消息的最大字节数（解压后），为0时使用DefaultWebSocketReadLimit，小于0表示不限制。
+	// 升级后可以通过WebSocketConn.SetReadLimit修改。
+	ReadLimit int64
+}
+
+// handshakeError 返回握手失败的响应并生成对应的错误。
+func handshakeError(c *Context, status int, reason string) error {
+	err := errors.New("websocket: " + reason)
+	if status == http.StatusUpgradeRequired {
+		c.SetHeader("Sec-WebSocket-Version", "13")
+	}
+	c.Fail(status, http.StatusText(status))
+	return err
+}
+
+// headerContainsToken 判断逗号分隔的请求头中是否包含某个token（忽略大小写）。
+func headerContainsToken(header http.Header, name, token string) bool {
+	for _, value := range header.Values(name) {
+		for _, t := range strings.Split(value, ",") {
+			if strings.EqualFold(strings.TrimSpace(t), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// checkSameOrigin 只允许没有Origin的请求，或Origin的主机与请求的Host相同。
+func checkSameOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	u, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+	return strings.EqualFold(u.Host, r.Host)
+}
+
+// selectSubprotocol 在客户端请求的子协议中选出服务端支持的第一个。
+func (u *WebSocketUpgrader) selectSubprotocol(r *http.Request) string {
+	var requested []string
+	for _, value := range r.Header.Values("Sec-WebSocket-Protocol") {
+		for _, p := range strings.Split(value, ",") {
+			requested = append(requested, strings.TrimSpace(p))
+		}
+	}
+	for _, supported := range u.Subprotocols {
+		for _, p := range requested {
+			if p == supported {
+				return p
+			}
+		}
+	}
+	return ""
+}
+
+// offersDeflate 判断客户端是否在Sec-WebSocket-Extensions中提供了permessage-deflate。
+func offersDeflate(r *http.Request) bool {
+	for _, value := range r.Header.Values("Sec-WebSocket-Extensions") {
+		for _, ext := range strings.Split(value, ",") {
+			name := strings.TrimSpace(strings.Split(ext, ";")[0])
+			if strings.EqualFold(name, "permessage-deflate") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// websocketAccept 根据Sec-WebSocket-Key计算Sec-WebSocket-Accept。
+func websocketAccept(key string) string {
+	h := sha1.New()
+	h.Write([]byte(key + websocketGUID))
+	return base64.StdEncoding.EncodeToString(h.Sum(nil))
+}
+
+// Upgrade 完成WebSocket握手并接管底层连接。
+// 握手失败时已经向客户端写出了错误响应，调用方只需返回。
+// responseHeader 中的字段会附加到101响应中，例如Set-Cookie。
+func (u *WebSocketUpgrader) Upgrade(c *Context, responseHeader http.Header) (*WebSocketConn, error) {
+	r := c.Req
+	if r.Method != http.MethodGet {
+		return nil, handshakeError(c, http.StatusMethodNotAllowed, "request method is not GET")
+	}
+	if !headerContainsToken(r.Header, "Connection", "upgrade") {
+		return nil, handshakeError(c, http.StatusBadRequest, "'upgrade' token not found in 'Connection' header")
+	}
+	if !headerContainsToken(r.Header, "Upgrade", "websocket") {
+		return nil, handshakeError(c, http.StatusBadRequest, "'websocket' token not found in 'Upgrade' header")
+	}
+	if r.Header.Get("Sec-WebSocket-Version") != "13" {
+		return nil, handshakeError(c, http.StatusUpgradeRequired, "unsupported version")
+	}
+	checkOrigin := u.CheckOrigin
+	if checkOrigin == nil {
+		checkOrigin = checkSameOrigin
+	}
+	if !checkOrigin(r) {
+		return nil, handshakeError(c, http.StatusForbidden, "request origin not allowed")
+	}
+	key := r.Header.Get("Sec-WebSocket-Key")
+	if decoded, err := base64.StdEncoding.DecodeString(key); err != nil || len(decoded) != 16 {
+		return nil, handshakeError(c, http.StatusBadRequest, "'Sec-WebSocket-Key' header is missing or invalid")
+	}
+
+	subprotocol := u.selectSubprotocol(r)
+	compress := u.EnableCompression && offersDeflate(r)
+
+	netConn, brw, err := c.Writer.Hijack()
+	if err != nil {
+		return nil, handshakeError(c, http.StatusInternalServerError, err.Error())
+	}
+	if brw.Reader.Buffered() > 0 {
+		netConn.Close()
+		return nil, errors.New("websocket: client sent data before handshake is complete")
+	}
+
+	var b strings.Builder
+	b.WriteString("HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n")
+	b.WriteString("Sec-WebSocket-Accept: " + websocketAccept(key) + "\r\n")
+	if subprotocol != "" {
+		b.WriteString("Sec-WebSocket-Protocol: " + subprotocol + "\r\n")
+	}
+	if compress {
+		b.WriteString("Sec-WebSocket-Extensions: permessage-deflate; server_no_context_takeover; client_no_context_takeover\r\n")
+	}
+	for k, vs := range responseHeader {
+		if k == "Sec-Websocket-Protocol" || k == "Sec-Websocket-Extensions" {
+			continue
+		}
+		for _, v := range vs {
+			b.WriteString(k + ": " + strings.NewReplacer("\r", "", "\n", "").Replace(v) + "\r\n")
+		}
+	}
+	b.WriteString("\r\n")
+
+	if u.HandshakeTimeout > 0 {
+		netConn.SetWriteDeadline(time.Now().Add(u.HandshakeTimeout))
+	}
+	if _, err := io.WriteString(netConn, b.String()); err != nil {
+		netConn.Close()
+		return nil, err
+	}
+	if u.HandshakeTimeout > 0 {
+		netConn.SetWriteDeadline(time.Time{})
+	}
+
+	readSize := u.ReadBufferSize
+	if readSize <= 0 {
+		readSize = 4096
+	}
+	ws := newWebSocketConn(netConn, bufio.NewReaderSize(netConn, readSize), u.WriteBufferSize)
+	ws.subprotocol = subprotocol
+	ws.compression = compress
+	ws.writeCompress = compress
+	ws.fragmentSize = u.FragmentSize
+	ws.readLimit = u.ReadLimit
+	if ws.readLimit == 0 {
+		ws.readLimit = DefaultWebSocketReadLimit
+	}
+	return ws, nil
+}
+
+// WebSocketConn 是服务端的WebSocket连接。
+// 同一时刻只能有一个goroutine读取消息；写操作内部加锁，可以并发调用。
+type WebSocketConn struct {
+	conn        net.Conn
+	br          *bufio.Reader
+	subprotocol string
+
+	writeMu       sync.Mutex
+	writeBuf      []byte
+	closeSent     bool
+	compression   bool // compression 表示是否协商了permessage-deflate
+	writeCompress bool // writeCompress 表示写出数据消息时是否压缩
+	compressLevel int
+	fragmentSize  int
+
+	readLimit    int64
+	pingHandler  func(data string) error
+	pongHandler  func(data string) error
+	closeHandler func(code int, text string) error
+}
+
+func newWebSocketConn(conn net.Conn, br *bufio.Reader, writeBufferSize int) *WebSocketConn {
+	if writeBufferSize <= 0 {
+		writeBufferSize = 4096
+	}
+	ws := &WebSocketConn{
+		conn:          conn,
+		br:            br,
+		writeBuf:      make([]byte, 0, writeBufferSize),
+		compressLevel: flate.BestSpeed,
+	}
+	ws.SetPingHandler(nil)
+	ws.SetPongHandler(nil)
+	ws.SetCloseHandler(nil)
+	return ws
+}
+
+// Subprotocol 返回握手时协商的子协议。
+func (ws *WebSocketConn) Subprotocol() string {
+	return ws.subprotocol
+}
+
+// RemoteAddr 返回对端的网络地址。
+func (ws *WebSocketConn) RemoteAddr() net.Addr {
+	return ws.conn.RemoteAddr()
+}
+
+// Close 直接关闭底层连接，不发送关闭帧。
+func (ws *WebSocketConn) Close() error {
+	return ws.conn.Close()
+}
+
+// SetReadDeadline 设置读取的截止时间。
+func (ws *WebSocketConn) SetReadDeadline(t time.Time) error {
+	return ws.conn.SetReadDeadline(t)
+}
+
+// SetWriteDeadline 设置写出的截止时间。
+func (ws *WebSocketConn) SetWriteDeadline(t time.Time) error {
+	return ws.conn.SetWriteDeadline(t)
+}
+
+// SetReadLimit 设置单条消息的最大字节数（解压后），超过时以1009关闭连接，小于等于0表示不限制。
+func (ws *WebSocketConn) SetReadLimit(limit int64) {
+	ws.readLimit = limit
+}
+
+// EnableWriteCompression 在已协商permessage-deflate时开启或关闭后续消息的压缩。
+func (ws *WebSocketConn) EnableWriteCompression(enable bool) {
+	ws.writeCompress = enable && ws.compression
+}
+
+// SetCompressionLevel 设置压缩级别，取值同compress/flate。
+func (ws *WebSocketConn) SetCompressionLevel(level int) error {
+	if level < flate.HuffmanOnly || level > flate.BestCompression {
+		return errors.New("websocket: invalid compression level")
+	}
+	ws.compressLevel = level
+	return nil
+}
+
+// SetPingHandler 设置收到ping时的处理函数，nil表示使用默认处理：回复内容相同的pong。
+func (ws *WebSocketConn) SetPingHandler(h func(data string) error) {
+	if h == nil {
+		h = func(data string) error {
+			err := ws.WriteControl(PongMessage, []byte(data), time.Now().Add(defaultControlWait))
+			if err == ErrCloseSent {
+				return nil
+			}
+			var ne net.Error
+			if errors.As(err, &ne) && ne.Timeout() {
+				return nil
+			}
+			return err
+		}
+	}
+	ws.pingHandler = h
+}
+
+// SetPongHandler 设置收到pong时的处理函数，常用于刷新读超时，nil表示忽略。
+func (ws *WebSocketConn) SetPongHandler(h func(data string) error) {
+	if h == nil {
+		h = func(string) error { return nil }
+	}
+	ws.pongHandler = h
+}
+
+// SetCloseHandler 设置收到关闭帧时的处理函数，nil表示使用默认处理：回复相同关闭码的关闭帧。
+func (ws *WebSocketConn) SetCloseHandler(h func(code int, text string) error) {
+	if h == nil {
+		h = func(code int, text string) error {
+			ws.WriteControl(CloseMessage, FormatCloseMessage(code, ""), time.Now().Add(defaultControlWait))
+			return nil
+		}
+	}
+	ws.closeHandler = h
+}
+
+// WriteClose 发送关闭帧，之后不能再写出数据消息。
+func (ws *WebSocketConn) WriteClose(code int, text string) error {
+	return ws.WriteControl(CloseMessage, FormatCloseMessage(code, text), time.Now().Add(defaultControlWait))
+}
+
+// WriteControl 写出一个控制帧（close、ping或pong），deadline为零值时不设置写超时。
+func (ws *WebSocketConn) WriteControl(messageType int, data []byte, deadline time.Time) error {
+	if messageType != CloseMessage && messageType != PingMessage && messageType != PongMessage {
+		return errors.New("websocket: bad control message type")
+	}
+	if len(data) > maxControlPayload {
+		return errors.New("websocket: control frame too long")
+	}
+	ws.writeMu.Lock()
+	defer ws.writeMu.Unlock()
+	if ws.closeSent {
+		return ErrCloseSent
+	}
+	if !deadline.IsZero() {
+		ws.conn.SetWriteDeadline(deadline)
+		defer ws.conn.SetWriteDeadline(time.Time{})
+	}
+	if messageType == CloseMessage {
+		ws.closeSent = true
+	}
+	return ws.writeFrame(true, false, messageType, data)
+}
+
+// WriteMessage 写出一条数据消息或控制消息。
+// 协商了压缩时数据消息会被压缩；设置了FragmentSize时长消息会被拆分为多个帧。
+func (ws *WebSocketConn) WriteMessage(messageType int, data []byte) error {
+	switch messageType {
+	case CloseMessage, PingMessage, PongMessage:
+		return ws.WriteControl(messageType, data, time.Time{})
+	case TextMessage, BinaryMessage:
+	default:
+		return errors.New("websocket: bad data message type")
+	}
+
+	ws.writeMu.Lock()
+	defer ws.writeMu.Unlock()
+	if ws.closeSent {
+		return ErrCloseSent
+	}
+	compressed := false
+	if ws.writeCompress {
+		var err error
+		if data, err = ws.deflate(data); err != nil {
+			return err
+		}
+		compressed = true
+	}
+
+	opcode := messageType
+	for first := true; first || len(data) > 0; first = false {
+		chunk := data
+		if ws.fragmentSize > 0 && len(chunk) > ws.fragmentSize {
+			chunk = chunk[:ws.fragmentSize]
+		}
+		data = data[len(chunk):]
+		if err := ws.writeFrame(len(data) == 0, compressed && first, opcode, chunk); err != nil {
+			return err
+		}
+		opcode = continuationFrame
+	}
+	return nil
+}
+
+// writeFrame 编码并写出一个服务端帧，服务端发出的帧不加掩码。调用方需持有writeMu。
+func (ws *WebSocketConn) writeFrame(fin, rsv1 bool, opcode int, payload []byte) error {
+	buf := ws.writeBuf[:0]
+	b0 := byte(opcode)
+	if fin {
+		b0 |= 0x80
+	}
+	if rsv1 {
+		b0 |= 0x40
+	}
+	buf = append(buf, b0)
+	switch n := len(payload); {
+	case n <= 125:
+		buf = append(buf, byte(n))
+	case n <= 0xffff:
+		buf = append(buf, 126, byte(n>>8), byte(n))
+	default:
+		buf = append(buf, 127)
+		buf = binary.BigEndian.AppendUint64(buf, uint64(n))
+	}
+	if cap(buf)-len(buf) >= len(payload) {
+		buf = append(buf, payload...)
+		ws.writeBuf = buf
+		_, err := ws.conn.Write(buf)
+		return err
+	}
+	ws.writeBuf = buf
+	_, err := (&net.Buffers{buf, payload}).WriteTo(ws.conn)
+	return err
+}
+
+// deflate 按permessage-deflate压缩消息，并去掉结尾的 0x00 0x00 0xff 0xff。
+func (ws *WebSocketConn) deflate(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	fw, err := flate.NewWriter(&buf, ws.compressLevel)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := fw.Write(data); err != nil {
+		return nil, err
+	}
+	if err := fw.Flush(); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte{0x00, 0x00, 0xff, 0xff}), nil
+}
+
+// inflateTail 是解压时补回的同步标记和一个空的最终块。
+var inflateTail = []byte{0x00, 0x00, 0xff, 0xff, 0x01, 0x00, 0x00, 0xff, 0xff}
+
+// inflate 解压permessage-deflate消息，limit大于0时限制解压后的大小。
+func inflate(data []byte, limit int64) ([]byte, error) {
+	var r io.Reader = flate.NewReader(io.MultiReader(bytes.NewReader(data), bytes.NewReader(inflateTail)))
+	if limit > 0 {
+		r = io.LimitReader(r, limit+1)
+	}
+	out, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	if limit > 0 && int64(len(out)) > limit {
+		return nil, ErrReadLimit
+	}
+	return out, nil
+}
+
+// frame 是读取到的一个帧。
+type frame struct {
+	fin     bool
+	rsv1    bool
+	opcode  int
+	payload []byte
+}
+
+// protocolError 发送带有关闭码的关闭帧，并返回对应的CloseError。
+func (ws *WebSocketConn) protocolError(code int, text string) error {
+	ws.WriteControl(CloseMessage, FormatCloseMessage(code, text), time.Now().Add(defaultControlWait))
+	return &CloseError{Code: code, Text: text}
+}
+
+// readFrame 读取并校验一个客户端帧，remaining为当前消息还允许读取的字节数（小于0表示不限制）。
+func (ws *WebSocketConn) readFrame(remaining int64) (*frame, error) {
+	var head [2]byte
+	if _, err := io.ReadFull(ws.br, head[:]); err != nil {
+		return nil, err
+	}
+	f := &frame{
+		fin:    head[0]&0x80 != 0,
+		rsv1:   head[0]&0x40 != 0,
+		opcode: int(head[0] & 0x0f),
+	}
+	if head[0]&0x30 != 0 {
+		return nil, ws.protocolError(CloseProtocolError, "unexpected reserved bits")
+	}
+	masked := head[1]&0x80 != 0
+	length := int64(head[1] & 0x7f)
+	switch length {
+	case 126:
+		var ext [2]byte
+		if _, err := io.ReadFull(ws.br, ext[:]); err != nil {
+			return nil, err
+		}
+		length = int64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		if _, err := io.ReadFull(ws.br, ext[:]); err != nil {
+			return nil, err
+		}
+		n := binary.BigEndian.Uint64(ext[:])
+		if n>>63 != 0 {
+			return nil, ws.protocolError(CloseProtocolError, "invalid payload length")
+		}
+		length = int64(n)
+	}
+
+	isControl := f.opcode >= CloseMessage
+	switch {
+	case !masked:
+		return nil, ws.protocolError(CloseProtocolError, "client frame is not masked")
+	case isControl && (length > maxControlPayload || !f.fin):
+		return nil, ws.protocolError(CloseProtocolError, "invalid control frame")
+	case isControl && f.rsv1:
+		return nil, ws.protocolError(CloseProtocolError, "unexpected reserved bits")
+	case !isControl && remaining >= 0 && length > remaining:
+		ws.protocolError(CloseMessageTooBig, "")
+		return nil, ErrReadLimit
+	}
+
+	var mask [4]byte
+	if _, err := io.ReadFull(ws.br, mask[:]); err != nil {
+		return nil, err
+	}
+	payload, err := readPayload(ws.br, length)
+	if err != nil {
+		return nil, err
+	}
+	f.payload = payload
+	for i := range f.payload {
+		f.payload[i] ^= mask[i&3]
+	}
+	return f, nil
+}
+
+// readPayload 读取length字节的负载。按块分配内存，声明了很大长度却不发送数据的帧
+// 只会占用实际收到的字节数。
+func readPayload(r io.Reader, length int64) ([]byte, error) {
+	if length <= payloadChunkSize {
+		p := make([]byte, length)
+		_, err := io.ReadFull(r, p)
+		return p, err
+	}
+	var buf bytes.Buffer
+	buf.Grow(payloadChunkSize)
+	if _, err := io.CopyN(&buf, r, length); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// validCloseCode 判断对端发来的关闭码是否合法，1004至1006和1015是保留码，不能出现在关闭帧中。
+func validCloseCode(code int) bool {
+	switch {
+	case code >= 1000 && code <= 1003, code >= 1007 && code <= 1014:
+		return true
+	case code >= 3000 && code <= 4999:
+		return true
+	}
+	return false
+}
+
+// handleControl 处理读取过程中收到的控制帧，收到关闭帧时返回CloseError。
+func (ws *WebSocketConn) handleControl(f *frame) error {
+	switch f.opcode {
+	case PingMessage:
+		return ws.pingHandler(string(f.payload))
+	case PongMessage:
+		return ws.pongHandler(string(f.payload))
+	}
+	code, text := CloseNoStatusReceived, ""
+	if len(f.payload) == 1 {
+		return ws.protocolError(CloseProtocolError, "invalid close payload")
+	}
+	if len(f.payload) >= 2 {
+		code = int(binary.BigEndian.Uint16(f.payload))
+		text = string(f.payload[2:])
+		if !validCloseCode(code) {
+			return ws.protocolError(CloseProtocolError, "invalid close code")
+		}
+		if !utf8.ValidString(text) {
+			return ws.protocolError(CloseInvalidFramePayloadData, "invalid utf8 payload in close frame")
+		}
+	}
+	if err := ws.closeHandler(code, text); err != nil {
+		return err
+	}
+	return &CloseError{Code: code, Text: text}
+}
+
+// ReadMessage 读取下一条完整的数据消息，自动拼接分片并处理期间收到的控制帧。
+// 收到关闭帧时返回*CloseError，之后不应再调用ReadMessage。
+func (ws *WebSocketConn) ReadMessage() (messageType int, p []byte, err error) {
+	var (
+		buf        []byte
+		compressed bool
+	)
+	for {
+		remaining := int64(-1)
+		if ws.readLimit > 0 {
+			remaining = ws.readLimit - int64(len(buf))
+		}
+		f, err := ws.readFrame(remaining)
+		if err != nil {
+			return 0, nil, err
+		}
+		switch f.opcode {
+		case CloseMessage, PingMessage, PongMessage:
+			if err := ws.handleControl(f); err != nil {
+				return 0, nil, err
+			}
+			continue
+		case TextMessage, BinaryMessage:
+			if messageType != 0 {
+				return 0, nil, ws.protocolError(CloseProtocolError, "expected continuation frame")
+			}
+			if f.rsv1 && !ws.compression {
+				return 0, nil, ws.protocolError(CloseProtocolError, "unexpected reserved bits")
+			}
+			messageType, compressed = f.opcode, f.rsv1
+		case continuationFrame:
+			if messageType == 0 {
+				return 0, nil, ws.protocolError(CloseProtocolError, "unexpected continuation frame")
+			}
+			if f.rsv1 {
+				return 0, nil, ws.protocolError(CloseProtocolError, "unexpected reserved bits")
+			}
+		default:
+			return 0, nil, ws.protocolError(CloseProtocolError, fmt.Sprintf("unknown opcode %d", f.opcode))
+		}
+		buf = append(buf, f.payload...)
+		if !f.fin {
+			continue
+		}
+		if compressed {
+			if buf, err = inflate(buf, ws.readLimit); err != nil {
+				if err == ErrReadLimit {
+					ws.protocolError(CloseMessageTooBig, "")
+					return 0, nil, err
+				}
+				return 0, nil, ws.protocolError(CloseInvalidFramePayloadData, "invalid compressed data")
+			}
+		}
+		if messageType == TextMessage && !utf8.Valid(buf) {
+			return 0, nil, ws.protocolError(CloseInvalidFramePayloadData, "invalid utf8 payload")
+		}
+		if buf == nil {
+			buf = []byte{}
+		}
+		return messageType, buf, nil
+	}
+}
+
+// ReadJSON 读取下一条消息并解析为JSON。
+func (ws *WebSocketConn) ReadJSON(v interface{}) error {
+	_, p, err := ws.ReadMessage()
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(p, v)
+}
+
+// WriteJSON 将v编码为JSON并作为文本消息写出。
+func (ws *WebSocketConn) WriteJSON(v interface{}) error {
+	p, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return ws.WriteMessage(TextMessage, p)
+}
@@ -0,0 +1,326 @@
+package gee
+
+import (
+	"bufio"
+	"bytes"
+	"compress/flate"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// clientFrame 按客户端的格式（带掩码）编码一个帧，length小于0时使用len(p)作为声明的长度。
+func clientFrame(fin, rsv1 bool, opcode int, p []byte, length int64) []byte {
+	b0 := byte(opcode)
+	if fin {
+		b0 |= 0x80
+	}
+	if rsv1 {
+		b0 |= 0x40
+	}
+	if length < 0 {
+		length = int64(len(p))
+	}
+	out := []byte{b0}
+	switch {
+	case length <= 125:
+		out = append(out, 0x80|byte(length))
+	case length <= 0xffff:
+		out = append(out, 0x80|126, byte(length>>8), byte(length))
+	default:
+		out = append(out, 0x80|127)
+		out = binary.BigEndian.AppendUint64(out, uint64(length))
+	}
+	mask := []byte{1, 2, 3, 4}
+	out = append(out, mask...)
+	for i, c := range p {
+		out = append(out, c^mask[i&3])
+	}
+	return out
+}
+
+// wsClient 是测试用的最小WebSocket客户端。
+type wsClient struct {
+	t    *testing.T
+	conn net.Conn
+	br   *bufio.Reader
+}
+
+// dialWebSocket 连接srv的path并完成握手，extensions为Sec-WebSocket-Extensions请求头。
+func dialWebSocket(t *testing.T, srv *httptest.Server, path, extensions string) (*wsClient, *http.Response) {
+	t.Helper()
+	host := strings.TrimPrefix(srv.URL, "http://")
+	conn, err := net.Dial("tcp", host)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+	req := "GET " + path + " HTTP/1.1\r\nHost: " + host + "\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n" +
+		"Sec-WebSocket-Key: dGhlIHNhbXBsZSBub25jZQ==\r\nSec-WebSocket-Version: 13\r\n"
+	if extensions != "" {
+		req += "Sec-WebSocket-Extensions: " + extensions + "\r\n"
+	}
+	if _, err := io.WriteString(conn, req+"\r\n"); err != nil {
+		t.Fatal(err)
+	}
+	br := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(br, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &wsClient{t: t, conn: conn, br: br}, resp
+}
+
+func (c *wsClient) write(frames ...[]byte) {
+	for _, f := range frames {
+		if _, err := c.conn.Write(f); err != nil {
+			c.t.Fatal(err)
+		}
+	}
+}
+
+// readFrame 读取服务端发送的一个帧。
+func (c *wsClient) readFrame() (byte, []byte) {
+	c.t.Helper()
+	head := make([]byte, 2)
+	if _, err := io.ReadFull(c.br, head); err != nil {
+		c.t.Fatal(err)
+	}
+	n := uint64(head[1] & 0x7f)
+	switch n {
+	case 126:
+		ext := make([]byte, 2)
+		io.ReadFull(c.br, ext)
+		n = uint64(binary.BigEndian.Uint16(ext))
+	case 127:
+		ext := make([]byte, 8)
+		io.ReadFull(c.br, ext)
+		n = binary.BigEndian.Uint64(ext)
+	}
+	p := make([]byte, n)
+	if _, err := io.ReadFull(c.br, p); err != nil {
+		c.t.Fatal(err)
+	}
+	return head[0], p
+}
+
+// readMessage 读取并拼接一条可能分片的消息。
+func (c *wsClient) readMessage() (byte, []byte) {
+	c.t.Helper()
+	first, msg := c.readFrame()
+	b0 := first
+	for b0&0x80 == 0 {
+		var p []byte
+		b0, p = c.readFrame()
+		msg = append(msg, p...)
+	}
+	return first, msg
+}
+
+// readClose 读取关闭帧并返回其中的关闭码。
+func (c *wsClient) readClose() int {
+	c.t.Helper()
+	b0, p := c.readFrame()
+	if b0&0x0f != CloseMessage || len(p) < 2 {
+		c.t.Fatalf("got frame %x %q, want close", b0, p)
+	}
+	return int(binary.BigEndian.Uint16(p))
+}
+
+// echoServer 启动一个回显消息的WebSocket服务，ReadMessage的错误发送到errs。
+func echoServer(t *testing.T, up *WebSocketUpgrader, errs chan<- error) *httptest.Server {
+	r := New()
+	r.GET("/ws", func(c *Context) {
+		ws, err := up.Upgrade(c, nil)
+		if err != nil {
+			return
+		}
+		defer ws.Close()
+		for {
+			mt, p, err := ws.ReadMessage()
+			if err != nil {
+				if errs != nil {
+					errs <- err
+				}
+				return
+			}
+			ws.WriteMessage(mt, p)
+		}
+	})
+	srv := httptest.NewServer(r)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestWebSocketHandshake(t *testing.T) {
+	srv := echoServer(t, &WebSocketUpgrader{Subprotocols: []string{"chat"}}, nil)
+
+	resp, err := http.Get(srv.URL + "/ws")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("plain GET = %d, want 400", resp.StatusCode)
+	}
+
+	_, resp = dialWebSocket(t, srv, "/ws", "")
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		t.Fatalf("status = %d, want 101", resp.StatusCode)
+	}
+	if got := resp.Header.Get("Sec-WebSocket-Accept"); got != "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=" {
+		t.Fatalf("Sec-WebSocket-Accept = %q", got)
+	}
+	if resp.Header.Get("Sec-WebSocket-Extensions") != "" {
+		t.Fatal("compression negotiated although it is disabled")
+	}
+}
+
+func TestWebSocketEcho(t *testing.T) {
+	srv := echoServer(t, &WebSocketUpgrader{}, nil)
+	c, _ := dialWebSocket(t, srv, "/ws", "")
+
+	// 分片的文本消息，中间插入一个ping
+	c.write(
+		clientFrame(false, false, TextMessage, []byte("hello "), -1),
+		clientFrame(true, false, PingMessage, []byte("p"), -1),
+		clientFrame(true, false, continuationFrame, []byte("world"), -1),
+	)
+	if b0, p := c.readFrame(); b0 != 0x80|PongMessage || string(p) != "p" {
+		t.Fatalf("got %x %q, want pong", b0, p)
+	}
+	if b0, msg := c.readMessage(); b0&0x0f != TextMessage || string(msg) != "hello world" {
+		t.Fatalf("got %x %q", b0, msg)
+	}
+
+	c.write(clientFrame(true, false, CloseMessage, FormatCloseMessage(CloseNormalClosure, "bye"), -1))
+	if code := c.readClose(); code != CloseNormalClosure {
+		t.Fatalf("close code = %d", code)
+	}
+}
+
+func TestWebSocketCompression(t *testing.T) {
+	srv := echoServer(t, &WebSocketUpgrader{EnableCompression: true}, nil)
+	c, resp := dialWebSocket(t, srv, "/ws", "permessage-deflate; client_max_window_bits")
+	if !strings.HasPrefix(resp.Header.Get("Sec-WebSocket-Extensions"), "permessage-deflate") {
+		t.Fatalf("extensions = %q", resp.Header.Get("Sec-WebSocket-Extensions"))
+	}
+
+	var buf bytes.Buffer
+	fw, _ := flate.NewWriter(&buf, flate.BestSpeed)
+	fw.Write([]byte("compressed hello"))
+	fw.Flush()
+	c.write(clientFrame(true, true, TextMessage, bytes.TrimSuffix(buf.Bytes(), []byte{0, 0, 0xff, 0xff}), -1))
+
+	b0, msg := c.readMessage()
+	if b0&0x40 == 0 {
+		t.Fatal("echo is not compressed")
+	}
+	out, err := inflate(msg, 0)
+	if err != nil || string(out) != "compressed hello" {
+		t.Fatalf("inflate = %q, %v", out, err)
+	}
+}
+
+func TestWebSocketDefaultReadLimit(t *testing.T) {
+	errs := make(chan error, 1)
+	srv := echoServer(t, &WebSocketUpgrader{}, errs)
+	c, _ := dialWebSocket(t, srv, "/ws", "")
+
+	// 只发送帧头，声明一个远超默认限制的负载长度
+	c.write(clientFrame(true, false, BinaryMessage, nil, 1<<62))
+	if code := c.readClose(); code != CloseMessageTooBig {
+		t.Fatalf("close code = %d, want %d", code, CloseMessageTooBig)
+	}
+	if err := <-errs; err != ErrReadLimit {
+		t.Fatalf("ReadMessage = %v, want ErrReadLimit", err)
+	}
+}
+
+func TestWebSocketReadLimit(t *testing.T) {
+	errs := make(chan error, 1)
+	srv := echoServer(t, &WebSocketUpgrader{ReadLimit: 8}, errs)
+	c, _ := dialWebSocket(t, srv, "/ws", "")
+
+	// 每个分片都不超过限制，但整条消息超过
+	c.write(
+		clientFrame(false, false, TextMessage, []byte("12345"), -1),
+		clientFrame(true, false, continuationFrame, []byte("67890"), -1),
+	)
+	if code := c.readClose(); code != CloseMessageTooBig {
+		t.Fatalf("close code = %d, want %d", code, CloseMessageTooBig)
+	}
+	if err := <-errs; err != ErrReadLimit {
+		t.Fatalf("ReadMessage = %v, want ErrReadLimit", err)
+	}
+}
+
+func TestWebSocketProtocolErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		frame []byte
+		code  int
+	}{
+		{"unmasked", []byte{0x81, 0x01, 'x'}, CloseProtocolError},
+		{"reserved bits", clientFrame(true, true, TextMessage, []byte("x"), -1), CloseProtocolError},
+		{"unexpected continuation", clientFrame(true, false, continuationFrame, []byte("x"), -1), CloseProtocolError},
+		{"fragmented ping", clientFrame(false, false, PingMessage, nil, -1), CloseProtocolError},
+		{"invalid utf8", clientFrame(true, false, TextMessage, []byte{0xff, 0xfe}, -1), CloseInvalidFramePayloadData},
+	}
+	for _, tt := range tests {
+		errs := make(chan error, 1)
+		srv := echoServer(t, &WebSocketUpgrader{}, errs)
+		c, _ := dialWebSocket(t, srv, "/ws", "")
+		c.write(tt.frame)
+		if code := c.readClose(); code != tt.code {
+			t.Errorf("%s: close code = %d, want %d", tt.name, code, tt.code)
+		}
+		if err := <-errs; !IsCloseError(err, tt.code) {
+			t.Errorf("%s: ReadMessage = %v", tt.name, err)
+		}
+	}
+}
+
+func TestValidCloseCode(t *testing.T) {
+	for code, want := range map[int]bool{
+		999:                    false,
+		CloseNormalClosure:     true,
+		CloseUnsupportedData:   true,
+		1004:                   false,
+		CloseNoStatusReceived:  false,
+		CloseAbnormalClosure:   false,
+		CloseInternalServerErr: true,
+		CloseServiceRestart:    true,
+		CloseTryAgainLater:     true,
+		CloseBadGateway:        true,
+		1015:                   false,
+		2999:                   false,
+		3000:                   true,
+		4999:                   true,
+		5000:                   false,
+	} {
+		if got := validCloseCode(code); got != want {
+			t.Errorf("validCloseCode(%d) = %v, want %v", code, got, want)
+		}
+	}
+}
+
+func TestReadPayloadIsBounded(t *testing.T) {
+	// 声明的长度远大于实际数据时，只读取实际收到的数据后返回错误
+	_, err := readPayload(strings.NewReader("abc"), 1<<50)
+	if !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Fatalf("err = %v, want io.ErrUnexpectedEOF", err)
+	}
+	data := bytes.Repeat([]byte("x"), payloadChunkSize*2+1)
+	p, err := readPayload(bytes.NewReader(data), int64(len(data)))
+	if err != nil || !bytes.Equal(p, data) {
+		t.Fatalf("large payload: len = %d, err = %v", len(p), err)
+	}
+}
@@ -0,0 +1,149 @@
+// Package geetest 提供测试gee处理函数和中间件的辅助函数，请求在内存中完成，不需要监听端口。
+package geetest
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+
+	"Gee/gee"
+)
+
+// CreateTestContext 创建一个绑定到新Engine的Context和用于记录响应的ResponseRecorder。
+// req为nil时使用 GET / 请求。可以配合Context.RunHandlers直接测试中间件。
+func CreateTestContext(req *http.Request) (*gee.Context, *Response, *gee.Engine) {
+	if req == nil {
+		req = httptest.NewRequest(http.MethodGet, "/", nil)
+	}
+	engine := gee.New()
+	w := &Response{ResponseRecorder: httptest.NewRecorder()}
+	return engine.NewContext(w, req), w, engine
+}
+
+// PerformRequest 构造请求并交给handler（通常是*gee.Engine）处理，返回记录下来的响应。
+// body可以为nil，headers中的字段会被添加到请求中。
+func PerformRequest(handler http.Handler, method, path string, body io.Reader, headers http.Header) *Response {
+	req := httptest.NewRequest(method, path, body)
+	for k, vs := range headers {
+		for _, v := range vs {
+			req.Header.Add(k, v)
+		}
+	}
+	return Do(handler, req)
+}
+
+// Do 把已经构造好的请求交给handler处理。
+func Do(handler http.Handler, req *http.Request) *Response {
+	w := &Response{ResponseRecorder: httptest.NewRecorder()}
+	handler.ServeHTTP(w, req)
+	return w
+}
+
+// NewJSONRequest 构造一个以JSON编码v作为请求体的请求。
+func NewJSONRequest(method, path string, v interface{}) *http.Request {
+	data, err := json.Marshal(v)
+	if err != nil {
+		panic(err)
+	}
+	req := httptest.NewRequest(method, path, bytes.NewReader(data))
+	req.Header.Set("Content-Type", "application/json")
+	return req
+}
+
+// NewFormRequest 构造一个application/x-www-form-urlencoded表单请求。
+func NewFormRequest(method, path string, form url.Values) *http.Request {
+	req := httptest.NewRequest(method, path, strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return req
+}
+
+// File 是multipart请求中上传的一个文件。
+type File struct {
+	Field    string // 表单字段名
+	Filename string // 文件名
+	Content  []byte // 文件内容
+}
+
+// NewMultipartRequest 构造一个multipart/form-data请求，包含普通字段和上传的文件。
+func NewMultipartRequest(method, path string, fields map[string]string, files ...File) *http.Request {
+	var buf bytes.Buffer
+	mw := multipart.NewWriter(&buf)
+	for k, v := range fields {
+		if err := mw.WriteField(k, v); err != nil {
+			panic(err)
+		}
+	}
+	for _, f := range files {
+		fw, err := mw.CreateFormFile(f.Field, f.Filename)
+		if err != nil {
+			panic(err)
+		}
+		fw.Write(f.Content)
+	}
+	if err := mw.Close(); err != nil {
+		panic(err)
+	}
+	req := httptest.NewRequest(method, path, &buf)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	return req
+}
+
+// Client 在多次请求之间保存服务端设置的Cookie，用于测试登录、会话等需要Cookie往返的流程。
+type Client struct {
+	Handler http.Handler
+	Header  http.Header // 每个请求都会带上的请求头
+	cookies map[string]*http.Cookie
+}
+
+// NewClient 创建一个把请求发送给handler的Client。
+func NewClient(handler http.Handler) *Client {
+	return &Client{Handler: handler, Header: make(http.Header), cookies: make(map[string]*http.Cookie)}
+}
+
+// Do 发送请求：附加保存的Cookie，并记录响应中的Set-Cookie（MaxAge<0的Cookie会被删除）。
+func (cl *Client) Do(req *http.Request) *Response {
+	for k, vs := range cl.Header {
+		if req.Header.Get(k) == "" {
+			for _, v := range vs {
+				req.Header.Add(k, v)
+			}
+		}
+	}
+	for _, c := range cl.cookies {
+		req.AddCookie(c)
+	}
+	resp := Do(cl.Handler, req)
+	for _, c := range resp.Result().Cookies() {
+		if c.MaxAge < 0 {
+			delete(cl.cookies, c.Name)
+			continue
+		}
+		cl.cookies[c.Name] = c
+	}
+	return resp
+}
+
+// Get 发送GET请求。
+func (cl *Client) Get(path string) *Response {
+	return cl.Do(httptest.NewRequest(http.MethodGet, path, nil))
+}
+
+// PostJSON 以JSON请求体发送POST请求。
+func (cl *Client) PostJSON(path string, v interface{}) *Response {
+	return cl.Do(NewJSONRequest(http.MethodPost, path, v))
+}
+
+// PostForm 以表单发送POST请求。
+func (cl *Client) PostForm(path string, form url.Values) *Response {
+	return cl.Do(NewFormRequest(http.MethodPost, path, form))
+}
+
+// Cookie 返回Client当前保存的Cookie，不存在时返回nil。
+func (cl *Client) Cookie(name string) *http.Cookie {
+	return cl.cookies[name]
+}
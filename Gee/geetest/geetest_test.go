@@ -0,0 +1,156 @@
+package geetest
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"Gee/gee"
+)
+
+func TestMain(m *testing.M) {
+	gee.SetMode(gee.TestMode)
+	m.Run()
+}
+
+// recordingTB 记录断言失败的信息，用于测试断言本身。
+type recordingTB struct {
+	testing.TB
+	errors []string
+}
+
+func (r *recordingTB) Helper() {}
+
+func (r *recordingTB) Errorf(format string, args ...interface{}) {
+	r.errors = append(r.errors, fmt.Sprintf(format, args...))
+}
+
+func newSessionEngine() *gee.Engine {
+	r := gee.New()
+	r.POST("/login", func(c *gee.Context) {
+		http.SetCookie(c.Writer, &http.Cookie{Name: "sid", Value: c.PostForm("user")})
+		c.JSON(http.StatusOK, gee.H{"ok": true})
+	})
+	r.POST("/logout", func(c *gee.Context) {
+		http.SetCookie(c.Writer, &http.Cookie{Name: "sid", MaxAge: -1})
+		c.Status(http.StatusNoContent)
+	})
+	r.GET("/me", func(c *gee.Context) {
+		sid, err := c.Req.Cookie("sid")
+		if err != nil {
+			c.String(http.StatusUnauthorized, "anonymous")
+			return
+		}
+		c.String(http.StatusOK, "%s %s", sid.Value, c.Req.Header.Get("X-Trace"))
+	})
+	return r
+}
+
+func TestClientKeepsCookies(t *testing.T) {
+	cl := NewClient(newSessionEngine())
+	cl.Header.Set("X-Trace", "t1")
+
+	cl.Get("/me").Expect(t).Status(http.StatusUnauthorized).Body("anonymous")
+	cl.PostForm("/login", url.Values{"user": {"bob"}}).Expect(t).
+		Status(http.StatusOK).
+		JSON(gee.H{"ok": true}).
+		Cookie("sid", "bob")
+	if c := cl.Cookie("sid"); c == nil || c.Value != "bob" {
+		t.Fatalf("Cookie(sid) = %v", c)
+	}
+	cl.Get("/me").Expect(t).Status(http.StatusOK).Body("bob t1")
+
+	cl.Do(NewJSONRequest(http.MethodPost, "/logout", nil)).Expect(t).Status(http.StatusNoContent)
+	if c := cl.Cookie("sid"); c != nil {
+		t.Fatalf("cookie was not removed after logout: %v", c)
+	}
+	cl.Get("/me").Expect(t).Status(http.StatusUnauthorized)
+}
+
+func TestRequestBuilders(t *testing.T) {
+	type payload struct {
+		Name string `json:"name"`
+	}
+	r := gee.New()
+	r.POST("/json", func(c *gee.Context) {
+		data, _ := io.ReadAll(c.Req.Body)
+		c.String(http.StatusOK, "%s %s", c.Req.Header.Get("Content-Type"), data)
+	})
+	r.POST("/upload", func(c *gee.Context) {
+		f, header, err := c.Req.FormFile("file")
+		if err != nil {
+			c.Fail(http.StatusBadRequest, err.Error())
+			return
+		}
+		defer f.Close()
+		data, _ := io.ReadAll(f)
+		c.String(http.StatusOK, "%s %s %s", c.Req.FormValue("title"), header.Filename, data)
+	})
+	r.GET("/header", func(c *gee.Context) {
+		c.SetHeader("X-Echo", c.Req.Header.Get("X-Token"))
+		c.String(http.StatusOK, "ok")
+	})
+
+	Do(r, NewJSONRequest(http.MethodPost, "/json", payload{Name: "gee"})).Expect(t).
+		Body(`application/json {"name":"gee"}`)
+	req := NewMultipartRequest(http.MethodPost, "/upload", map[string]string{"title": "notes"},
+		File{Field: "file", Filename: "a.txt", Content: []byte("hello")})
+	Do(r, req).Expect(t).Status(http.StatusOK).Body("notes a.txt hello")
+	PerformRequest(r, http.MethodGet, "/header", nil, http.Header{"X-Token": {"abc"}}).Expect(t).
+		Header("X-Echo", "abc").
+		HeaderContains("Content-Type", "text/plain").
+		NoHeader("X-Missing").
+		BodyContains("o")
+}
+
+func TestCreateTestContext(t *testing.T) {
+	c, w, engine := CreateTestContext(nil)
+	if engine == nil || c.Req.URL.Path != "/" {
+		t.Fatalf("CreateTestContext(nil) = %v %v", engine, c.Req.URL)
+	}
+	c.RunHandlers(func(c *gee.Context) {
+		c.SetHeader("X-Middleware", "before")
+		c.Next()
+	}, func(c *gee.Context) {
+		c.String(http.StatusCreated, "created")
+	})
+	w.Expect(t).Status(http.StatusCreated).Header("X-Middleware", "before").Body("created")
+
+	var got map[string]interface{}
+	c, w, _ = CreateTestContext(nil)
+	c.JSON(http.StatusOK, gee.H{"n": 1})
+	w.Expect(t).DecodeJSON(&got)
+	if got["n"] != float64(1) {
+		t.Fatalf("DecodeJSON = %v", got)
+	}
+}
+
+func TestExpectationFailures(t *testing.T) {
+	c, w, _ := CreateTestContext(nil)
+	c.SetHeader("X-Set", "1")
+	c.String(http.StatusOK, "plain")
+
+	rec := &recordingTB{TB: t}
+	w.Expect(rec).
+		Status(http.StatusNotFound).
+		Header("X-Set", "2").
+		HeaderContains("X-Set", "3").
+		NoHeader("X-Set").
+		Body("other").
+		BodyContains("missing").
+		JSON(gee.H{}).
+		Cookie("sid", "x")
+	if len(rec.errors) != 8 {
+		t.Fatalf("got %d failures, want 8: %q", len(rec.errors), rec.errors)
+	}
+
+	c, w, _ = CreateTestContext(nil)
+	c.JSON(http.StatusOK, gee.H{"a": 1})
+	rec = &recordingTB{TB: t}
+	w.Expect(rec).JSON(gee.H{"a": 1}).JSON(gee.H{"a": 2})
+	if len(rec.errors) != 1 {
+		t.Fatalf("got %d failures, want 1: %q", len(rec.errors), rec.errors)
+	}
+}
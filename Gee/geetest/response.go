@@ -0,0 +1,129 @@
+package geetest
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// Response 是记录下来的响应，通过Expect进行链式断言。
+type Response struct {
+	*httptest.ResponseRecorder
+}
+
+// Expect 返回针对该响应的断言，断言失败时通过t.Errorf报告，不会中断测试。
+func (r *Response) Expect(t testing.TB) *Expectation {
+	return &Expectation{t: t, r: r}
+}
+
+// DecodeJSON 把响应体解析为JSON。
+func (r *Response) DecodeJSON(v interface{}) error {
+	return json.Unmarshal(r.Body.Bytes(), v)
+}
+
+// Expectation 提供链式调用的响应断言。
+type Expectation struct {
+	t testing.TB
+	r *Response
+}
+
+// Status 断言状态码。
+func (e *Expectation) Status(code int) *Expectation {
+	e.t.Helper()
+	if e.r.Code != code {
+		e.t.Errorf("status = %d, want %d; body: %s", e.r.Code, code, e.r.Body.String())
+	}
+	return e
+}
+
+// Header 断言响应头的值。
+func (e *Expectation) Header(key, want string) *Expectation {
+	e.t.Helper()
+	if got := e.r.Header().Get(key); got != want {
+		e.t.Errorf("header %s = %q, want %q", key, got, want)
+	}
+	return e
+}
+
+// HeaderContains 断言响应头的值包含sub。
+func (e *Expectation) HeaderContains(key, sub string) *Expectation {
+	e.t.Helper()
+	if got := e.r.Header().Get(key); !strings.Contains(got, sub) {
+		e.t.Errorf("header %s = %q, want it to contain %q", key, got, sub)
+	}
+	return e
+}
+
+// NoHeader 断言响应中没有该响应头。
+func (e *Expectation) NoHeader(key string) *Expectation {
+	e.t.Helper()
+	if got, ok := e.r.Header()[http.CanonicalHeaderKey(key)]; ok {
+		e.t.Errorf("header %s = %q, want it to be absent", key, got)
+	}
+	return e
+}
+
+// Body 断言响应体与want完全相同。
+func (e *Expectation) Body(want string) *Expectation {
+	e.t.Helper()
+	if got := e.r.Body.String(); got != want {
+		e.t.Errorf("body = %q, want %q", got, want)
+	}
+	return e
+}
+
+// BodyContains 断言响应体包含sub。
+func (e *Expectation) BodyContains(sub string) *Expectation {
+	e.t.Helper()
+	if got := e.r.Body.String(); !strings.Contains(got, sub) {
+		e.t.Errorf("body = %q, want it to contain %q", got, sub)
+	}
+	return e
+}
+
+// JSON 断言响应体是与want等价的JSON，want会先编码为JSON再比较，因此可以传入结构体或gee.H。
+func (e *Expectation) JSON(want interface{}) *Expectation {
+	e.t.Helper()
+	var got, expected interface{}
+	if err := json.Unmarshal(e.r.Body.Bytes(), &got); err != nil {
+		e.t.Errorf("body is not valid JSON: %v; body: %s", err, e.r.Body.String())
+		return e
+	}
+	data, err := json.Marshal(want)
+	if err != nil {
+		e.t.Errorf("cannot encode expected JSON: %v", err)
+		return e
+	}
+	json.Unmarshal(data, &expected)
+	if !reflect.DeepEqual(got, expected) {
+		e.t.Errorf("JSON body = %s, want %s", strings.TrimSpace(e.r.Body.String()), data)
+	}
+	return e
+}
+
+// DecodeJSON 把响应体解析到v中，解析失败时报告错误。
+func (e *Expectation) DecodeJSON(v interface{}) *Expectation {
+	e.t.Helper()
+	if err := e.r.DecodeJSON(v); err != nil {
+		e.t.Errorf("cannot decode JSON body: %v; body: %s", err, e.r.Body.String())
+	}
+	return e
+}
+
+// Cookie 断言响应设置了名为name、值为value的Cookie。
+func (e *Expectation) Cookie(name, value string) *Expectation {
+	e.t.Helper()
+	for _, c := range e.r.Result().Cookies() {
+		if c.Name == name {
+			if c.Value != value {
+				e.t.Errorf("cookie %s = %q, want %q", name, c.Value, value)
+			}
+			return e
+		}
+	}
+	e.t.Errorf("cookie %s was not set", name)
+	return e
+}
@@ -0,0 +1,112 @@
+// Package middleware 提供可以直接挂载到gee路由上的通用中间件。
+package middleware
+
+import (
+	"crypto/subtle"
+	"encoding/base64"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"Gee/gee"
+)
+
+const (
+	// AuthUserKey 是Basic认证通过后，用户名在Context中保存的键。
+	AuthUserKey = "user"
+	// AuthTokenKey 是Bearer认证通过后，令牌在Context中保存的键。
+	AuthTokenKey = "token"
+)
+
+// Accounts 保存Basic认证的用户名和密码。
+type Accounts map[string]string
+
+// BasicAuth 返回使用默认realm的HTTP Basic认证中间件。
+func BasicAuth(accounts Accounts) gee.Handlerfunc {
+	return BasicAuthForRealm(accounts, "")
+}
+
+// BasicAuthForRealm 返回HTTP Basic认证中间件。
+// 密码使用常量时间比较，避免通过响应耗时猜测密码。
+// 认证失败时返回401，并在WWW-Authenticate中携带realm。
+func BasicAuthForRealm(accounts Accounts, realm string) gee.Handlerfunc {
+	if realm == "" {
+		realm = "Authorization Required"
+	}
+	challenge := "Basic realm=" + strconv.Quote(realm)
+	return func(c *gee.Context) {
+		user, pass, ok := c.Req.BasicAuth()
+		if !ok || !checkAccount(accounts, user, pass) {
+			c.SetHeader("WWW-Authenticate", challenge)
+			c.Fail(http.StatusUnauthorized, http.StatusText(http.StatusUnauthorized))
+			return
+		}
+		c.Set(AuthUserKey, user)
+		c.Next()
+	}
+}
+
+// checkAccount 检查用户名和密码是否匹配。
+// 即使用户不存在也会完成一次比较，使耗时与用户是否存在无关。
+func checkAccount(accounts Accounts, user, pass string) bool {
+	expected, found := accounts[user]
+	if !found {
+		expected = pass + "x"
+	}
+	match := subtle.ConstantTimeCompare([]byte(expected), []byte(pass)) == 1
+	return found && match
+}
+
+// BasicAuthHeader 生成Basic认证请求头的值，便于客户端和测试使用。
+func BasicAuthHeader(user, pass string) string {
+	return "Basic " + base64.StdEncoding.EncodeToString([]byte(user+":"+pass))
+}
+
+// BearerAuth 返回静态Bearer令牌认证中间件。
+// 请求必须携带 "Authorization: Bearer <token>"，且令牌属于tokens之一。
+func BearerAuth(realm string, tokens ...string) gee.Handlerfunc {
+	if realm == "" {
+		realm = "Authorization Required"
+	}
+	return func(c *gee.Context) {
+		token, ok := bearerToken(c.Req)
+		if !ok {
+			bearerChallenge(c, realm, "", "")
+			return
+		}
+		valid := 0
+		for _, t := range tokens {
+			valid |= subtle.ConstantTimeCompare([]byte(t), []byte(token))
+		}
+		if valid != 1 {
+			bearerChallenge(c, realm, "invalid_token", "the access token is invalid")
+			return
+		}
+		c.Set(AuthTokenKey, token)
+		c.Next()
+	}
+}
+
+// bearerToken 从Authorization请求头中取出Bearer令牌。
+func bearerToken(req *http.Request) (string, bool) {
+	auth := req.Header.Get("Authorization")
+	const prefix = "Bearer "
+	if len(auth) <= len(prefix) || !strings.EqualFold(auth[:len(prefix)], prefix) {
+		return "", false
+	}
+	return strings.TrimSpace(auth[len(prefix):]), true
+}
+
+// bearerChallenge 按RFC 6750返回401及WWW-Authenticate质询。
+// 未携带令牌时不附带error参数。
+func bearerChallenge(c *gee.Context, realm, code, desc string) {
+	challenge := "Bearer realm=" + strconv.Quote(realm)
+	if code != "" {
+		challenge += ", error=" + strconv.Quote(code)
+	}
+	if desc != "" {
+		challenge += ", error_description=" + strconv.Quote(desc)
+	}
+	c.SetHeader("WWW-Authenticate", challenge)
+	c.Fail(http.StatusUnauthorized, http.StatusText(http.StatusUnauthorized))
+}
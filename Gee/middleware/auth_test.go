@@ -0,0 +1,54 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"Gee/gee"
+)
+
+func TestBasicAuth(t *testing.T) {
+	r := gee.New()
+	r.Use(BasicAuthForRealm(Accounts{"admin": "secret"}, "admin area"))
+	r.GET("/", func(c *gee.Context) {
+		user, _ := c.Get(AuthUserKey)
+		c.String(http.StatusOK, "%v", user)
+	})
+
+	w := performRequest(r, http.MethodGet, "/", nil, "Authorization", BasicAuthHeader("admin", "secret"))
+	if w.Code != http.StatusOK || w.Body.String() != "admin" {
+		t.Fatalf("got %d %q", w.Code, w.Body.String())
+	}
+	for _, auth := range []string{"", BasicAuthHeader("admin", "wrong"), BasicAuthHeader("nobody", "secret")} {
+		w = performRequest(r, http.MethodGet, "/", nil, "Authorization", auth)
+		if w.Code != http.StatusUnauthorized {
+			t.Fatalf("auth %q: status = %d, want 401", auth, w.Code)
+		}
+		if got := w.Header().Get("WWW-Authenticate"); got != `Basic realm="admin area"` {
+			t.Fatalf("WWW-Authenticate = %q", got)
+		}
+	}
+}
+
+func TestBearerAuth(t *testing.T) {
+	r := gee.New()
+	r.Use(BearerAuth("", "t1", "t2"))
+	r.GET("/", func(c *gee.Context) {
+		token, _ := c.Get(AuthTokenKey)
+		c.String(http.StatusOK, "%v", token)
+	})
+
+	w := performRequest(r, http.MethodGet, "/", nil, "Authorization", "bearer t2")
+	if w.Code != http.StatusOK || w.Body.String() != "t2" {
+		t.Fatalf("got %d %q", w.Code, w.Body.String())
+	}
+	w = performRequest(r, http.MethodGet, "/", nil)
+	if w.Code != http.StatusUnauthorized || strings.Contains(w.Header().Get("WWW-Authenticate"), "error=") {
+		t.Fatalf("missing token: %d %q", w.Code, w.Header().Get("WWW-Authenticate"))
+	}
+	w = performRequest(r, http.MethodGet, "/", nil, "Authorization", "Bearer t3")
+	if w.Code != http.StatusUnauthorized || !strings.Contains(w.Header().Get("WWW-Authenticate"), `error="invalid_token"`) {
+		t.Fatalf("invalid token: %d %q", w.Code, w.Header().Get("WWW-Authenticate"))
+	}
+}
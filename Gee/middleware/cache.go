@@ -0,0 +1,441 @@
+package middleware
+
+import (
+	"bufio"
+	"bytes"
+	"container/list"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"Gee/gee"
+)
+
+// cacheTagsKey 是处理函数通过CacheTag添加的标签在Context中保存的键。
+const cacheTagsKey = "gee_cache_tags"
+
+// CacheConfig 用于配置响应缓存。
+type CacheConfig struct {
+	// TTL 响应没有Cache-Control max-age时的缓存时间，小于等于0时使用1分钟。
+	TTL time.Duration
+	// MaxEntries 最多缓存的响应数，超出后淘汰最久未使用的，小于等于0时使用1000。
+	MaxEntries int
+	// MaxBytes 缓存的响应体总字节数上限，为0时不限制。
+	MaxBytes int64
+	// MaxEntryBytes 单个响应体的最大字节数，超过的响应不缓存，小于等于0时使用1MB。
+	MaxEntryBytes int
+	// VaryHeaders 总是参与缓存键计算的请求头，例如 Accept-Language。
+	// 响应中Vary头列出的请求头也会自动参与计算。
+	VaryHeaders []string
+	// Tags 返回新缓存的响应所属的标签，用于InvalidateTag。处理函数也可以调用CacheTag添加标签。
+	Tags func(c *gee.Context) []string
+}
+
+// cacheEntry 是缓存的一个响应。
+type cacheEntry struct {
+	key     string
+	base    string
+	status  int
+	header  http.Header
+	body    []byte
+	stored  time.Time
+	expires time.Time
+	tags    []string
+}
+
+// cacheCall 是正在执行中的一次回源，相同缓存键的并发请求等待它的结果。
+type cacheCall struct {
+	done  chan struct{}
+	entry *cacheEntry // 响应不可缓存时为nil
+}
+
+// Cache 是一个进程内的HTTP响应缓存，按LRU淘汰，支持按键和标签失效。
+type Cache struct {
+	conf     CacheConfig
+	mu       sync.Mutex
+	lru      *list.List                     // 元素为*cacheEntry，越靠前越近使用
+	entries  map[string]*list.Element       // 完整缓存键到LRU元素
+	vary     map[string][]string            // 基础键到响应Vary头中列出的请求头
+	variants map[string]map[string]struct{} // 基础键到它的各个完整缓存键
+	tags     map[string]map[string]struct{} // 标签到完整缓存键
+	calls    map[string]*cacheCall          // 正在回源的缓存键
+	bytes    int64                          // 缓存的响应体总字节数
+}
+
+// NewCache 创建响应缓存，通过Middleware挂载到路由上。
+func NewCache(conf CacheConfig) *Cache {
+	if conf.TTL <= 0 {
+		conf.TTL = time.Minute
+	}
+	if conf.MaxEntries <= 0 {
+		conf.MaxEntries = 1000
+	}
+	if conf.MaxEntryBytes <= 0 {
+		conf.MaxEntryBytes = 1 << 20
+	}
+	for i, h := range conf.VaryHeaders {
+		conf.VaryHeaders[i] = http.CanonicalHeaderKey(h)
+	}
+	return &Cache{
+		conf:     conf,
+		lru:      list.New(),
+		entries:  make(map[string]*list.Element),
+		vary:     make(map[string][]string),
+		variants: make(map[string]map[string]struct{}),
+		tags:     make(map[string]map[string]struct{}),
+		calls:    make(map[string]*cacheCall),
+	}
+}
+
+// CacheTag 为当前响应添加缓存标签，在处理函数中调用。
+func CacheTag(c *gee.Context, tags ...string) {
+	prev, _ := c.Get(cacheTagsKey)
+	old, _ := prev.([]string)
+	c.Set(cacheTagsKey, append(old, tags...))
+}
+
+// CacheKey 返回请求的基础缓存键：路径加上按参数名排序的查询字符串。
+// Invalidate使用的就是这个键。
+func CacheKey(req *http.Request) string {
+	q := req.URL.Query().Encode()
+	if q == "" {
+		return req.URL.Path
+	}
+	return req.URL.Path + "?" + q
+}
+
+// Middleware 返回缓存中间件。只缓存GET请求的响应，HEAD请求使用GET缓存的响应头。
+// 遵守请求和响应中的Cache-Control：请求no-store时跳过缓存，no-cache时重新生成；
+// 响应no-store、no-cache、private或设置了Cookie时不缓存，max-age和s-maxage决定缓存时间。
+// 相同缓存键的并发未命中只有一个请求执行处理函数，其余请求等待并共享它的结果。
+func (s *Cache) Middleware() gee.Handlerfunc {
+	return func(c *gee.Context) {
+		if c.Method != http.MethodGet && c.Method != http.MethodHead {
+			c.Next()
+			return
+		}
+		reqCC := parseCacheControl(c.Req.Header.Get("Cache-Control"))
+		if reqCC.has("no-store") {
+			c.Next()
+			return
+		}
+		base := CacheKey(c.Req)
+
+		for {
+			s.mu.Lock()
+			key := s.key(base, c.Req)
+			if !reqCC.has("no-cache") {
+				if e := s.lookup(key); e != nil {
+					s.mu.Unlock()
+					s.serve(c, e)
+					return
+				}
+			}
+			if c.Method == http.MethodHead {
+				s.mu.Unlock()
+				c.Next()
+				return
+			}
+			call, waiting := s.calls[key]
+			if !waiting {
+				call = &cacheCall{done: make(chan struct{})}
+				s.calls[key] = call
+				s.mu.Unlock()
+				s.fill(c, base, key, call)
+				return
+			}
+			s.mu.Unlock()
+
+			select {
+			case <-call.done:
+			case <-c.Req.Context().Done():
+				c.Abort()
+				return
+			}
+			if call.entry == nil {
+				c.Next() // 响应不可缓存，各自执行处理函数
+				return
+			}
+			if call.entry.key == key {
+				s.serve(c, call.entry)
+				return
+			}
+			// 回源后得知响应按其他请求头区分（Vary），重新计算缓存键
+		}
+	}
+}
+
+// fill 执行处理函数，同时记录响应，可缓存时存入缓存并唤醒等待的请求。
+func (s *Cache) fill(c *gee.Context, base, key string, call *cacheCall) {
+	rec := &cacheRecorder{ResponseWriter: c.Writer, limit: s.conf.MaxEntryBytes}
+	c.Writer = rec
+	defer func() {
+		c.Writer = rec.ResponseWriter
+		s.mu.Lock()
+		delete(s.calls, key)
+		s.mu.Unlock()
+		close(call.done)
+	}()
+
+	c.Writer.Header().Set("X-Cache", "MISS")
+	c.Next()
+
+	status := rec.Status()
+	header := rec.Header()
+	respCC := parseCacheControl(header.Get("Cache-Control"))
+	// 处理函数没有写出任何内容时，响应由之后的中间件或http包补全，不知道最终的内容
+	if rec.uncacheable || !rec.Written() || !cacheableStatus(status) || header.Get("Set-Cookie") != "" ||
+		respCC.has("no-store") || respCC.has("no-cache") || respCC.has("private") ||
+		(c.Req.Header.Get("Authorization") != "" && !respCC.has("public") && !respCC.has("s-maxage")) {
+		return
+	}
+	ttl := s.conf.TTL
+	if v, ok := respCC["s-maxage"]; ok {
+		ttl = parseSeconds(v)
+	} else if v, ok := respCC["max-age"]; ok {
+		ttl = parseSeconds(v)
+	}
+	if ttl <= 0 {
+		return
+	}
+	var varyNames []string
+	for _, v := range header.Values("Vary") {
+		for _, name := range strings.Split(v, ",") {
+			name = http.CanonicalHeaderKey(strings.TrimSpace(name))
+			if name == "*" {
+				return
+			}
+			if name != "" {
+				varyNames = append(varyNames, name)
+			}
+		}
+	}
+
+	var tags []string
+	if s.conf.Tags != nil {
+		tags = append(tags, s.conf.Tags(c)...)
+	}
+	if v, ok := c.Get(cacheTagsKey); ok {
+		tags = append(tags, v.([]string)...)
+	}
+	now := time.Now()
+	e := &cacheEntry{
+		base:    base,
+		status:  status,
+		header:  header.Clone(),
+		body:    append([]byte(nil), rec.body.Bytes()...),
+		stored:  now,
+		expires: now.Add(ttl),
+		tags:    tags,
+	}
+	e.header.Del("X-Cache")
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sort.Strings(varyNames)
+	s.vary[base] = varyNames
+	e.key = s.key(base, c.Req)
+	s.store(e)
+	call.entry = e
+}
+
+// serve 把缓存的响应写给客户端。
+func (s *Cache) serve(c *gee.Context, e *cacheEntry) {
+	h := c.Writer.Header()
+	for k, v := range e.header {
+		h[k] = append([]string(nil), v...)
+	}
+	h.Set("Age", strconv.Itoa(int(time.Since(e.stored).Seconds())))
+	h.Set("X-Cache", "HIT")
+	c.Writer.WriteHeader(e.status)
+	if c.Method != http.MethodHead {
+		c.Writer.Write(e.body)
+	}
+	c.Abort()
+}
+
+// key 由基础键、配置的VaryHeaders和响应Vary头中的请求头计算完整的缓存键，调用者需持有s.mu。
+func (s *Cache) key(base string, req *http.Request) string {
+	names := append(append([]string(nil), s.conf.VaryHeaders...), s.vary[base]...)
+	if len(names) == 0 {
+		return base
+	}
+	var b strings.Builder
+	b.WriteString(base)
+	for _, name := range names {
+		b.WriteString("\n" + name + ":" + strings.Join(req.Header.Values(name), ","))
+	}
+	return b.String()
+}
+
+// lookup 返回未过期的缓存项并标记为最近使用，调用者需持有s.mu。
+func (s *Cache) lookup(key string) *cacheEntry {
+	el, ok := s.entries[key]
+	if !ok {
+		return nil
+	}
+	e := el.Value.(*cacheEntry)
+	if time.Now().After(e.expires) {
+		s.remove(el)
+		return nil
+	}
+	s.lru.MoveToFront(el)
+	return e
+}
+
+// store 存入缓存项，并按条数和字节数淘汰最久未使用的项，调用者需持有s.mu。
+func (s *Cache) store(e *cacheEntry) {
+	if el, ok := s.entries[e.key]; ok {
+		s.remove(el)
+	}
+	s.entries[e.key] = s.lru.PushFront(e)
+	s.bytes += int64(len(e.body))
+	if s.variants[e.base] == nil {
+		s.variants[e.base] = make(map[string]struct{})
+	}
+	s.variants[e.base][e.key] = struct{}{}
+	for _, tag := range e.tags {
+		if s.tags[tag] == nil {
+			s.tags[tag] = make(map[string]struct{})
+		}
+		s.tags[tag][e.key] = struct{}{}
+	}
+	for s.lru.Len() > s.conf.MaxEntries || (s.conf.MaxBytes > 0 && s.bytes > s.conf.MaxBytes && s.lru.Len() > 0) {
+		s.remove(s.lru.Back())
+	}
+}
+
+// remove 删除缓存项及其索引，调用者需持有s.mu。
+func (s *Cache) remove(el *list.Element) {
+	e := s.lru.Remove(el).(*cacheEntry)
+	delete(s.entries, e.key)
+	s.bytes -= int64(len(e.body))
+	if v := s.variants[e.base]; v != nil {
+		delete(v, e.key)
+		if len(v) == 0 {
+			delete(s.variants, e.base)
+			delete(s.vary, e.base)
+		}
+	}
+	for _, tag := range e.tags {
+		if keys := s.tags[tag]; keys != nil {
+			delete(keys, e.key)
+			if len(keys) == 0 {
+				delete(s.tags, tag)
+			}
+		}
+	}
+}
+
+// Invalidate 删除基础键（参见CacheKey）对应的全部缓存响应，包括按请求头区分的各个变体。
+func (s *Cache) Invalidate(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for full := range s.variants[key] {
+		s.remove(s.entries[full])
+	}
+}
+
+// InvalidateTag 删除带有tag标签的全部缓存响应。
+func (s *Cache) InvalidateTag(tag string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for full := range s.tags[tag] {
+		s.remove(s.entries[full])
+	}
+}
+
+// Purge 清空缓存。
+func (s *Cache) Purge() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for s.lru.Len() > 0 {
+		s.remove(s.lru.Back())
+	}
+}
+
+// Len 返回当前缓存的响应数。
+func (s *Cache) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.lru.Len()
+}
+
+// cacheRecorder 在写出响应的同时记录响应体。
+type cacheRecorder struct {
+	gee.ResponseWriter
+	body        bytes.Buffer
+	limit       int
+	uncacheable bool // 响应体过大、被Flush或连接被接管时不缓存
+}
+
+func (w *cacheRecorder) Write(data []byte) (int, error) {
+	if !w.uncacheable {
+		if w.body.Len()+len(data) > w.limit {
+			w.uncacheable = true
+			w.body.Reset()
+		} else {
+			w.body.Write(data)
+		}
+	}
+	return w.ResponseWriter.Write(data)
+}
+
+// Flush 表示这是流式响应，流式响应不缓存。
+func (w *cacheRecorder) Flush() {
+	w.uncacheable = true
+	w.ResponseWriter.Flush()
+}
+
+// Hijack 表示连接被接管（例如升级为WebSocket），之后的数据不经过记录器，响应不缓存。
+func (w *cacheRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	w.uncacheable = true
+	return w.ResponseWriter.Hijack()
+}
+
+func (w *cacheRecorder) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// cacheableStatus 判断状态码对应的响应能否缓存。
+func cacheableStatus(code int) bool {
+	switch code {
+	case http.StatusOK, http.StatusNonAuthoritativeInfo, http.StatusNoContent,
+		http.StatusMovedPermanently, http.StatusNotFound, http.StatusGone:
+		return true
+	}
+	return false
+}
+
+// cacheControl 是解析后的Cache-Control指令，指令名为小写。
+type cacheControl map[string]string
+
+func (cc cacheControl) has(directive string) bool {
+	_, ok := cc[directive]
+	return ok
+}
+
+func parseCacheControl(v string) cacheControl {
+	cc := make(cacheControl)
+	for _, part := range strings.Split(v, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		name, value, _ := strings.Cut(part, "=")
+		cc[strings.ToLower(strings.TrimSpace(name))] = strings.Trim(strings.TrimSpace(value), `"`)
+	}
+	return cc
+}
+
+func parseSeconds(v string) time.Duration {
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 0 {
+		return 0
+	}
+	return time.Duration(n) * time.Second
+}
@@ -0,0 +1,195 @@
+package middleware
+
+import (
+	"bufio"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"Gee/gee"
+)
+
+func TestCacheHitAndVary(t *testing.T) {
+	var hits atomic.Int32
+	cache := NewCache(CacheConfig{})
+	r := gee.New()
+	r.Use(cache.Middleware())
+	r.GET("/a", func(c *gee.Context) {
+		hits.Add(1)
+		time.Sleep(20 * time.Millisecond)
+		c.SetHeader("Vary", "Accept-Language")
+		c.String(http.StatusOK, "a:%s%s", c.Query("x"), c.Req.Header.Get("Accept-Language"))
+	})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			performRequest(r, http.MethodGet, "/a?x=1", nil, "Accept-Language", "en")
+		}()
+	}
+	wg.Wait()
+	if n := hits.Load(); n != 1 {
+		t.Fatalf("concurrent misses ran the handler %d times, want 1", n)
+	}
+
+	w := performRequest(r, http.MethodGet, "/a?x=1", nil, "Accept-Language", "en")
+	if w.Header().Get("X-Cache") != "HIT" || w.Body.String() != "a:1en" || w.Header().Get("Age") == "" {
+		t.Fatalf("hit = %v %q", w.Header(), w.Body)
+	}
+	if w := performRequest(r, http.MethodGet, "/a?x=1", nil, "Accept-Language", "fr"); w.Body.String() != "a:1fr" {
+		t.Fatalf("vary variant = %q", w.Body)
+	}
+	if w := performRequest(r, http.MethodHead, "/a?x=1", nil, "Accept-Language", "fr"); w.Body.Len() != 0 || w.Header().Get("X-Cache") != "HIT" {
+		t.Fatalf("HEAD = %v %q", w.Header(), w.Body)
+	}
+	if w := performRequest(r, http.MethodGet, "/a?x=1", nil, "Accept-Language", "en", "Cache-Control", "no-cache"); w.Header().Get("X-Cache") != "MISS" {
+		t.Fatalf("no-cache request was served from cache: %v", w.Header())
+	}
+	if n := hits.Load(); n != 3 {
+		t.Fatalf("handler ran %d times, want 3", n)
+	}
+}
+
+func TestCacheSkipsUncacheable(t *testing.T) {
+	var hits atomic.Int32
+	cache := NewCache(CacheConfig{MaxEntryBytes: 8})
+	r := gee.New()
+	r.Use(cache.Middleware())
+	handlers := map[string]gee.Handlerfunc{
+		"/nostore": func(c *gee.Context) {
+			c.SetHeader("Cache-Control", "no-store")
+			c.String(http.StatusOK, "n")
+		},
+		"/private": func(c *gee.Context) {
+			c.SetHeader("Cache-Control", "private, max-age=60")
+			c.String(http.StatusOK, "p")
+		},
+		"/cookie": func(c *gee.Context) {
+			http.SetCookie(c.Writer, &http.Cookie{Name: "sid", Value: "1"})
+			c.String(http.StatusOK, "c")
+		},
+		"/error": func(c *gee.Context) { c.String(http.StatusInternalServerError, "e") },
+		"/large": func(c *gee.Context) { c.String(http.StatusOK, "0123456789") },
+		"/stream": func(c *gee.Context) {
+			c.String(http.StatusOK, "s")
+			c.Writer.Flush()
+		},
+		"/vary-all": func(c *gee.Context) {
+			c.SetHeader("Vary", "*")
+			c.String(http.StatusOK, "v")
+		},
+		"/unwritten": func(c *gee.Context) {},
+	}
+	for path, h := range handlers {
+		h := h
+		r.GET(path, func(c *gee.Context) {
+			hits.Add(1)
+			h(c)
+		})
+	}
+	for path := range handlers {
+		performRequest(r, http.MethodGet, path, nil)
+		performRequest(r, http.MethodGet, path, nil)
+	}
+	if cache.Len() != 0 {
+		t.Fatalf("cached %d uncacheable responses", cache.Len())
+	}
+	if n := hits.Load(); n != int32(2*len(handlers)) {
+		t.Fatalf("handler ran %d times, want %d", n, 2*len(handlers))
+	}
+
+	performRequest(r, http.MethodGet, "/error", nil, "Cache-Control", "no-store")
+	if w := performRequest(r, http.MethodPost, "/error", nil); w.Header().Get("X-Cache") != "" {
+		t.Fatalf("POST went through the cache: %v", w.Header())
+	}
+}
+
+func TestCacheSkipsHijackedConnections(t *testing.T) {
+	var hits atomic.Int32
+	cache := NewCache(CacheConfig{})
+	r := gee.New()
+	r.Use(cache.Middleware())
+	r.GET("/raw", func(c *gee.Context) {
+		hits.Add(1)
+		conn, rw, err := c.Writer.Hijack()
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		defer conn.Close()
+		rw.WriteString("HTTP/1.1 200 OK\r\nContent-Length: 3\r\nConnection: close\r\n\r\nraw")
+		rw.Flush()
+	})
+	srv := httptest.NewServer(r)
+	defer srv.Close()
+
+	for i := 0; i < 2; i++ {
+		resp, err := http.Get(srv.URL + "/raw")
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := io.ReadAll(bufio.NewReader(resp.Body))
+		resp.Body.Close()
+		if string(body) != "raw" {
+			t.Fatalf("body = %q", body)
+		}
+	}
+	if hits.Load() != 2 || cache.Len() != 0 {
+		t.Fatalf("hijacked response was cached: hits=%d len=%d", hits.Load(), cache.Len())
+	}
+}
+
+func TestCacheInvalidation(t *testing.T) {
+	cache := NewCache(CacheConfig{MaxEntries: 2, Tags: func(c *gee.Context) []string {
+		return []string{"all"}
+	}})
+	r := gee.New()
+	r.Use(cache.Middleware())
+	r.GET("/item", func(c *gee.Context) {
+		CacheTag(c, "item-"+c.Query("id"))
+		c.String(http.StatusOK, "item %s", c.Query("id"))
+	})
+	r.GET("/short", func(c *gee.Context) {
+		c.SetHeader("Cache-Control", "max-age=0")
+		c.String(http.StatusOK, "short")
+	})
+
+	for _, id := range []string{"1", "2", "3"} {
+		performRequest(r, http.MethodGet, "/item?id="+id, nil)
+	}
+	if cache.Len() != 2 {
+		t.Fatalf("LRU kept %d entries, want 2", cache.Len())
+	}
+	if w := performRequest(r, http.MethodGet, "/item?id=1", nil); w.Header().Get("X-Cache") != "MISS" {
+		t.Fatal("the least recently used entry was not evicted")
+	}
+	cache.Invalidate(CacheKey(httptest.NewRequest(http.MethodGet, "/item?id=1", nil)))
+	if cache.Len() != 1 {
+		t.Fatalf("Invalidate left %d entries", cache.Len())
+	}
+	cache.InvalidateTag("item-3")
+	if cache.Len() != 0 {
+		t.Fatalf("InvalidateTag left %d entries", cache.Len())
+	}
+	performRequest(r, http.MethodGet, "/item?id=4", nil)
+	performRequest(r, http.MethodGet, "/short", nil)
+	if cache.Len() != 1 {
+		t.Fatalf("max-age=0 response was cached, len=%d", cache.Len())
+	}
+	cache.InvalidateTag("all")
+	performRequest(r, http.MethodGet, "/item?id=5", nil)
+	cache.Purge()
+	if cache.Len() != 0 {
+		t.Fatalf("Purge left %d entries", cache.Len())
+	}
+
+	if got := CacheKey(httptest.NewRequest(http.MethodGet, "/p?b=2&a=1", nil)); got != "/p?a=1&b=2" {
+		t.Fatalf("CacheKey = %q", got)
+	}
+}
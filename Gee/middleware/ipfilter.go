@@ -0,0 +1,193 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"net/netip"
+	"strings"
+	"sync/atomic"
+
+	"Gee/gee"
+)
+
+// IPFilterConfig 用于配置IP访问控制。
+type IPFilterConfig struct {
+	// Allow 允许访问的IP地址或CIDR，例如 "203.0.113.0/24"、"2001:db8::/32"。为空时允许所有地址。
+	Allow []string
+	// Deny 拒绝访问的IP地址或CIDR，优先于Allow。
+	Deny []string
+	// Handler 拒绝访问时执行的处理函数，为nil时返回403。
+	Handler gee.Handlerfunc
+}
+
+// IPFilter 按客户端IP（Context.ClientIP，会考虑可信代理）控制访问，规则可以在运行时通过Reload替换。
+type IPFilter struct {
+	rules   atomic.Pointer[ipRules]
+	handler gee.Handlerfunc
+}
+
+// ipRules 是编译后的一组规则，替换时整体交换，读取时不需要加锁。
+type ipRules struct {
+	allow *prefixTrie // 为nil表示允许所有地址
+	deny  *prefixTrie
+}
+
+// NewIPFilter 创建IP访问控制，地址格式错误时返回错误。
+func NewIPFilter(conf IPFilterConfig) (*IPFilter, error) {
+	f := &IPFilter{handler: conf.Handler}
+	if f.handler == nil {
+		f.handler = func(c *gee.Context) {
+			c.Fail(http.StatusForbidden, http.StatusText(http.StatusForbidden))
+		}
+	}
+	if err := f.Reload(conf.Allow, conf.Deny); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// AllowIPs 返回只允许给定地址访问的中间件，地址格式错误时panic，适合在注册路由时直接使用：
+//
+//	admin := r.Group("/admin")
+//	admin.Use(middleware.AllowIPs("203.0.113.0/24", "10.8.0.0/16"))
+func AllowIPs(cidrs ...string) gee.Handlerfunc {
+	f, err := NewIPFilter(IPFilterConfig{Allow: cidrs})
+	if err != nil {
+		panic(err)
+	}
+	return f.Middleware()
+}
+
+// Reload 替换允许和拒绝列表，正在处理的请求不受影响。任何地址格式错误时返回错误，原有规则保持不变。
+func (f *IPFilter) Reload(allow, deny []string) error {
+	rules := &ipRules{deny: &prefixTrie{}}
+	if len(allow) > 0 {
+		rules.allow = &prefixTrie{}
+		if err := rules.allow.insertAll(allow); err != nil {
+			return err
+		}
+	}
+	if err := rules.deny.insertAll(deny); err != nil {
+		return err
+	}
+	f.rules.Store(rules)
+	return nil
+}
+
+// Allowed 判断IP地址是否允许访问：位于拒绝列表时不允许；允许列表非空时必须位于其中。
+// 无法解析的地址只有在两个列表都为空时才被允许。
+func (f *IPFilter) Allowed(ip string) bool {
+	rules := f.rules.Load()
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return rules.allow == nil && rules.deny.empty()
+	}
+	addr = addr.Unmap().WithZone("")
+	if rules.deny.contains(addr) {
+		return false
+	}
+	return rules.allow == nil || rules.allow.contains(addr)
+}
+
+// Middleware 返回执行访问控制的中间件。
+func (f *IPFilter) Middleware() gee.Handlerfunc {
+	return func(c *gee.Context) {
+		if !f.Allowed(c.ClientIP()) {
+			f.handler(c)
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// prefixTrie 是按位组织的前缀树，IPv4和IPv6分开存放，匹配的耗时只与地址长度有关。
+type prefixTrie struct {
+	v4, v6 *trieNode
+}
+
+type trieNode struct {
+	children [2]*trieNode
+	terminal bool // 从根到该节点的位构成一个完整的网段
+}
+
+func (t *prefixTrie) insertAll(cidrs []string) error {
+	for _, s := range cidrs {
+		prefix, err := parseCIDR(s)
+		if err != nil {
+			return fmt.Errorf("middleware: invalid IP or CIDR %q: %w", s, err)
+		}
+		t.insert(prefix)
+	}
+	return nil
+}
+
+func (t *prefixTrie) insert(prefix netip.Prefix) {
+	root := &t.v6
+	if prefix.Addr().Is4() {
+		root = &t.v4
+	}
+	if *root == nil {
+		*root = &trieNode{}
+	}
+	n := *root
+	b := prefix.Addr().AsSlice()
+	for i := 0; i < prefix.Bits(); i++ {
+		if n.terminal {
+			return // 已经被更短的网段覆盖
+		}
+		bit := b[i/8] >> (7 - i%8) & 1
+		if n.children[bit] == nil {
+			n.children[bit] = &trieNode{}
+		}
+		n = n.children[bit]
+	}
+	n.terminal = true
+	n.children = [2]*trieNode{} // 更长的网段已被覆盖
+}
+
+func (t *prefixTrie) contains(addr netip.Addr) bool {
+	n := t.v6
+	if addr.Is4() {
+		n = t.v4
+	}
+	b := addr.AsSlice()
+	for i := 0; n != nil; i++ {
+		if n.terminal {
+			return true
+		}
+		if i == len(b)*8 {
+			return false
+		}
+		n = n.children[b[i/8]>>(7-i%8)&1]
+	}
+	return false
+}
+
+func (t *prefixTrie) empty() bool {
+	return t.v4 == nil && t.v6 == nil
+}
+
+// parseCIDR 解析IP地址或CIDR，单个地址视为只包含它自己的网段，IPv4映射的IPv6地址按IPv4处理。
+func parseCIDR(s string) (netip.Prefix, error) {
+	s = strings.TrimSpace(s)
+	if !strings.Contains(s, "/") {
+		addr, err := netip.ParseAddr(s)
+		if err != nil {
+			return netip.Prefix{}, err
+		}
+		addr = addr.Unmap()
+		return netip.PrefixFrom(addr, addr.BitLen()), nil
+	}
+	prefix, err := netip.ParsePrefix(s)
+	if err != nil {
+		return netip.Prefix{}, err
+	}
+	if prefix.Addr().Is4In6() {
+		if prefix.Bits() < 96 {
+			return netip.Prefix{}, fmt.Errorf("prefix length too short for an IPv4-mapped address")
+		}
+		prefix = netip.PrefixFrom(prefix.Addr().Unmap(), prefix.Bits()-96)
+	}
+	return prefix.Masked(), nil
+}
@@ -0,0 +1,192 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+
+	"Gee/gee"
+)
+
+func TestPrefixTrie(t *testing.T) {
+	tests := []struct {
+		cidrs []string
+		in    []string
+		out   []string
+	}{
+		{
+			cidrs: []string{"10.0.0.0/8", "10.1.0.0/16"},
+			in:    []string{"10.0.0.1", "10.1.2.3", "10.255.255.255"},
+			out:   []string{"11.0.0.0", "9.255.255.255"},
+		},
+		{
+			// 先插入更长的网段，再被更短的网段覆盖
+			cidrs: []string{"192.168.1.0/24", "192.168.0.0/16"},
+			in:    []string{"192.168.1.1", "192.168.200.1"},
+			out:   []string{"192.169.0.1"},
+		},
+		{
+			cidrs: []string{"203.0.113.7", "203.0.113.9/32"},
+			in:    []string{"203.0.113.7", "203.0.113.9"},
+			out:   []string{"203.0.113.8", "203.0.113.6"},
+		},
+		{
+			cidrs: []string{"10.0.0.1/8"},
+			in:    []string{"10.200.0.1"},
+		},
+		{
+			cidrs: []string{"2001:db8::/32", "::ffff:172.16.0.0/108"},
+			in:    []string{"2001:db8:1::5", "172.16.3.4"},
+			out:   []string{"2001:db9::1", "172.32.0.1", "::ffff:172.16.3.4"},
+		},
+		{
+			cidrs: []string{"0.0.0.0/0"},
+			in:    []string{"1.2.3.4", "255.255.255.255"},
+			out:   []string{"::1"},
+		},
+		{
+			cidrs: nil,
+			out:   []string{"1.2.3.4", "::1"},
+		},
+	}
+	for _, tt := range tests {
+		trie := &prefixTrie{}
+		if err := trie.insertAll(tt.cidrs); err != nil {
+			t.Fatalf("insertAll(%v) = %v", tt.cidrs, err)
+		}
+		for _, ip := range tt.in {
+			if !trie.contains(netip.MustParseAddr(ip)) {
+				t.Errorf("%v should contain %s", tt.cidrs, ip)
+			}
+		}
+		for _, ip := range tt.out {
+			if trie.contains(netip.MustParseAddr(ip)) {
+				t.Errorf("%v should not contain %s", tt.cidrs, ip)
+			}
+		}
+	}
+}
+
+func TestParseCIDRErrors(t *testing.T) {
+	for _, bad := range []string{"", "garbage", "10.0.0.0/33", "2001:db8::/129", "::ffff:10.0.0.0/64"} {
+		if _, err := parseCIDR(bad); err == nil {
+			t.Errorf("parseCIDR(%q) accepted an invalid value", bad)
+		}
+	}
+}
+
+func TestIPFilterAllowed(t *testing.T) {
+	f, err := NewIPFilter(IPFilterConfig{
+		Allow: []string{"203.0.113.0/24", "2001:db8::/32", "10.0.0.0/8"},
+		Deny:  []string{"10.6.6.6", "2001:db8:bad::/48"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for ip, want := range map[string]bool{
+		"203.0.113.7":     true,
+		"203.0.114.1":     false,
+		"2001:db8:1::5":   true,
+		"2001:db8:bad::1": false,
+		"2001:db9::1":     false,
+		"10.1.2.3":        true,
+		"10.6.6.6":        false,
+		"::ffff:10.1.1.1": true,
+		"fe80::1%eth0":    false,
+		"garbage":         false,
+		"":                false,
+	} {
+		if got := f.Allowed(ip); got != want {
+			t.Errorf("Allowed(%q) = %v, want %v", ip, got, want)
+		}
+	}
+
+	open, _ := NewIPFilter(IPFilterConfig{})
+	if !open.Allowed("1.2.3.4") || !open.Allowed("garbage") {
+		t.Error("an empty filter should allow everything")
+	}
+	denyOnly, _ := NewIPFilter(IPFilterConfig{Deny: []string{"1.2.3.4"}})
+	if denyOnly.Allowed("1.2.3.4") || !denyOnly.Allowed("1.2.3.5") || denyOnly.Allowed("garbage") {
+		t.Error("deny-only filter gave wrong results")
+	}
+}
+
+func TestIPFilterReload(t *testing.T) {
+	f, err := NewIPFilter(IPFilterConfig{Allow: []string{"203.0.113.0/24"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewIPFilter(IPFilterConfig{Allow: []string{"10.0.0.0/33"}}); err == nil {
+		t.Fatal("NewIPFilter accepted an invalid CIDR")
+	}
+	if err := f.Reload([]string{"8.8.8.0/24"}, []string{"bad"}); err == nil {
+		t.Fatal("Reload accepted an invalid CIDR")
+	}
+	if !f.Allowed("203.0.113.7") || f.Allowed("8.8.8.8") {
+		t.Fatal("a failed Reload changed the rules")
+	}
+	if err := f.Reload([]string{"8.8.8.0/24"}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if f.Allowed("203.0.113.7") || !f.Allowed("8.8.8.8") {
+		t.Fatal("Reload did not replace the rules")
+	}
+}
+
+func TestIPFilterMiddleware(t *testing.T) {
+	f, err := NewIPFilter(IPFilterConfig{
+		Allow: []string{"203.0.113.0/24"},
+		Handler: func(c *gee.Context) {
+			c.JSON(http.StatusForbidden, gee.H{"ip": c.ClientIP()})
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := gee.New()
+	if err := r.SetTrustedProxies([]string{"127.0.0.1"}); err != nil {
+		t.Fatal(err)
+	}
+	admin := r.Group("/admin")
+	admin.Use(f.Middleware())
+	admin.GET("/x", func(c *gee.Context) { c.String(http.StatusOK, "ok") })
+	ops := r.Group("/ops")
+	ops.Use(AllowIPs("10.0.0.0/8"))
+	ops.GET("/x", func(c *gee.Context) { c.String(http.StatusOK, "ok") })
+
+	do := func(path, remote, xff string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.RemoteAddr = remote
+		if xff != "" {
+			req.Header.Set("X-Forwarded-For", xff)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+	tests := []struct {
+		path, remote, xff string
+		code              int
+		body              string
+	}{
+		{"/admin/x", "127.0.0.1:1", "203.0.113.9", http.StatusOK, "ok"},
+		{"/admin/x", "127.0.0.1:1", "8.8.8.8", http.StatusForbidden, `{"ip":"8.8.8.8"}`},
+		{"/admin/x", "8.8.8.8:1", "203.0.113.9", http.StatusForbidden, `{"ip":"8.8.8.8"}`},
+		{"/ops/x", "10.1.1.1:1", "", http.StatusOK, "ok"},
+		{"/ops/x", "8.8.8.8:1", "", http.StatusForbidden, ""},
+	}
+	for _, tt := range tests {
+		w := do(tt.path, tt.remote, tt.xff)
+		if w.Code != tt.code || (tt.body != "" && w.Body.String() != tt.body && w.Body.String() != tt.body+"\n") {
+			t.Errorf("GET %s from %s (XFF %q) = %d %q, want %d %q", tt.path, tt.remote, tt.xff, w.Code, w.Body, tt.code, tt.body)
+		}
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("AllowIPs accepted an invalid CIDR")
+		}
+	}()
+	AllowIPs("not-an-ip")
+}
@@ -0,0 +1,252 @@
+package middleware
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"hash"
+	"strings"
+	"time"
+
+	"Gee/gee"
+)
+
+// JWTClaimsKey 是JWT校验通过后，声明（claims）在Context中保存的键。
+const JWTClaimsKey = "jwt_claims"
+
+// JWT校验过程中可能返回的错误。
+var (
+	ErrTokenMalformed   = errors.New("jwt: token is malformed")
+	ErrTokenSecret      = errors.New("jwt: secret is empty")
+	ErrTokenAlgorithm   = errors.New("jwt: unexpected signing algorithm")
+	ErrTokenSignature   = errors.New("jwt: signature is invalid")
+	ErrTokenExpired     = errors.New("jwt: token is expired")
+	ErrTokenNotValidYet = errors.New("jwt: token is not valid yet")
+	ErrTokenAudience    = errors.New("jwt: token audience is invalid")
+	ErrTokenIssuer      = errors.New("jwt: token issuer is invalid")
+)
+
+// Claims 是JWT载荷中的声明集合。
+type Claims map[string]interface{}
+
+// Subject 返回sub声明，不存在时返回空字符串。
+func (cl Claims) Subject() string {
+	s, _ := cl["sub"].(string)
+	return s
+}
+
+// JWTConfig 用于配置JWT校验中间件。
+type JWTConfig struct {
+	// Secret HMAC签名使用的密钥，不能为空。
+	Secret []byte
+	// Algorithms 允许的签名算法，为空时允许HS256、HS384和HS512。
+	Algorithms []string
+	// Audience 非空时要求aud声明包含该值。
+	Audience string
+	// Issuer 非空时要求iss声明等于该值。
+	Issuer string
+	// Leeway 校验exp和nbf时允许的时钟偏差。
+	Leeway time.Duration
+	// Realm 认证失败时WWW-Authenticate中的realm。
+	Realm string
+}
+
+// hashFor 返回签名算法对应的哈希函数。
+func hashFor(alg string) func() hash.Hash {
+	switch alg {
+	case "HS256":
+		return sha256.New
+	case "HS384":
+		return sha512.New384
+	case "HS512":
+		return sha512.New
+	}
+	return nil
+}
+
+// JWT 返回JWT Bearer令牌校验中间件。
+// 令牌从 "Authorization: Bearer <token>" 中读取，校验通过后声明保存在JWTClaimsKey下，
+// sub声明同时保存在AuthUserKey下。Secret为空时panic，避免密钥配置缺失时任何人都能伪造令牌。
+func JWT(conf JWTConfig) gee.Handlerfunc {
+	if len(conf.Secret) == 0 {
+		panic("middleware: JWT secret must not be empty")
+	}
+	realm := conf.Realm
+	if realm == "" {
+		realm = "Authorization Required"
+	}
+	return func(c *gee.Context) {
+		token, ok := bearerToken(c.Req)
+		if !ok {
+			bearerChallenge(c, realm, "", "")
+			return
+		}
+		claims, err := ParseJWT(token, conf)
+		if err != nil {
+			bearerChallenge(c, realm, "invalid_token", err.Error())
+			return
+		}
+		c.Set(JWTClaimsKey, claims)
+		if sub := claims.Subject(); sub != "" {
+			c.Set(AuthUserKey, sub)
+		}
+		c.Next()
+	}
+}
+
+// ParseJWT 校验令牌签名以及exp、nbf、aud、iss声明，成功时返回声明集合。
+// Secret为空时返回ErrTokenSecret；exp或nbf存在但不是数字时返回ErrTokenMalformed。
+func ParseJWT(token string, conf JWTConfig) (Claims, error) {
+	if len(conf.Secret) == 0 {
+		return nil, ErrTokenSecret
+	}
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, ErrTokenMalformed
+	}
+	var header struct {
+		Alg string `json:"alg"`
+	}
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, ErrTokenMalformed
+	}
+	if !algorithmAllowed(header.Alg, conf.Algorithms) {
+		return nil, ErrTokenAlgorithm
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, ErrTokenMalformed
+	}
+	mac := hmac.New(hashFor(header.Alg), conf.Secret)
+	mac.Write([]byte(parts[0] + "." + parts[1]))
+	if !hmac.Equal(sig, mac.Sum(nil)) {
+		return nil, ErrTokenSignature
+	}
+	var claims Claims
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, ErrTokenMalformed
+	}
+	if err := claims.validate(conf); err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+// validate 检查时间相关声明以及aud、iss声明。
+func (cl Claims) validate(conf JWTConfig) error {
+	now := time.Now()
+	exp, hasExp, err := cl.numeric("exp")
+	if err != nil {
+		return err
+	}
+	if hasExp && !now.Before(exp.Add(conf.Leeway)) {
+		return ErrTokenExpired
+	}
+	nbf, hasNbf, err := cl.numeric("nbf")
+	if err != nil {
+		return err
+	}
+	if hasNbf && now.Add(conf.Leeway).Before(nbf) {
+		return ErrTokenNotValidYet
+	}
+	if conf.Audience != "" && !cl.hasAudience(conf.Audience) {
+		return ErrTokenAudience
+	}
+	if conf.Issuer != "" {
+		if iss, _ := cl["iss"].(string); iss != conf.Issuer {
+			return ErrTokenIssuer
+		}
+	}
+	return nil
+}
+
+// numeric 将NumericDate类型的声明转换为时间，声明不存在时返回false，
+// 存在但不是数字时返回ErrTokenMalformed，避免格式错误的exp使令牌永不过期。
+func (cl Claims) numeric(name string) (time.Time, bool, error) {
+	raw, ok := cl[name]
+	if !ok {
+		return time.Time{}, false, nil
+	}
+	v, ok := raw.(float64)
+	if !ok {
+		return time.Time{}, false, ErrTokenMalformed
+	}
+	sec := int64(v)
+	return time.Unix(sec, int64((v-float64(sec))*float64(time.Second))), true, nil
+}
+
+// hasAudience 判断aud声明是否包含指定值，aud可以是字符串或字符串数组。
+func (cl Claims) hasAudience(aud string) bool {
+	switch v := cl["aud"].(type) {
+	case string:
+		return v == aud
+	case []interface{}:
+		for _, a := range v {
+			if s, ok := a.(string); ok && s == aud {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// algorithmAllowed 判断签名算法是否受支持且在允许列表中。
+func algorithmAllowed(alg string, allowed []string) bool {
+	if hashFor(alg) == nil {
+		return false
+	}
+	if len(allowed) == 0 {
+		return true
+	}
+	for _, a := range allowed {
+		if a == alg {
+			return true
+		}
+	}
+	return false
+}
+
+// decodeSegment 解码base64url编码的JSON片段。
+func decodeSegment(seg string, v interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(seg)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// SignJWT 使用HMAC算法签发令牌，alg可以是HS256、HS384或HS512，secret为空时返回ErrTokenSecret。
+func SignJWT(alg string, claims Claims, secret []byte) (string, error) {
+	if len(secret) == 0 {
+		return "", ErrTokenSecret
+	}
+	h := hashFor(alg)
+	if h == nil {
+		return "", ErrTokenAlgorithm
+	}
+	header, err := json.Marshal(map[string]string{"alg": alg, "typ": "JWT"})
+	if err != nil {
+		return "", err
+	}
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+	signing := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	mac := hmac.New(h, secret)
+	mac.Write([]byte(signing))
+	return signing + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil)), nil
+}
+
+// ClaimsFromContext 取出JWT中间件保存的声明。
+func ClaimsFromContext(c *gee.Context) (Claims, bool) {
+	v, ok := c.Get(JWTClaimsKey)
+	if !ok {
+		return nil, false
+	}
+	claims, ok := v.(Claims)
+	return claims, ok
+}
@@ -0,0 +1,111 @@
+package middleware
+
+import (
+	"encoding/base64"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"Gee/gee"
+)
+
+var testSecret = []byte("test-secret")
+
+func signTest(t *testing.T, alg string, claims Claims) string {
+	t.Helper()
+	token, err := SignJWT(alg, claims, testSecret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+func TestParseJWT(t *testing.T) {
+	future := float64(time.Now().Add(time.Hour).Unix())
+	past := float64(time.Now().Add(-time.Hour).Unix())
+	conf := JWTConfig{Secret: testSecret, Audience: "api", Issuer: "gee"}
+
+	tests := []struct {
+		name   string
+		alg    string
+		claims Claims
+		want   error
+	}{
+		{"valid", "HS256", Claims{"sub": "u1", "exp": future, "aud": "api", "iss": "gee"}, nil},
+		{"audience array", "HS512", Claims{"aud": []string{"web", "api"}, "iss": "gee"}, nil},
+		{"expired", "HS256", Claims{"exp": past, "aud": "api", "iss": "gee"}, ErrTokenExpired},
+		{"not valid yet", "HS384", Claims{"nbf": future, "aud": "api", "iss": "gee"}, ErrTokenNotValidYet},
+		{"wrong audience", "HS256", Claims{"aud": "web", "iss": "gee"}, ErrTokenAudience},
+		{"wrong issuer", "HS256", Claims{"aud": "api", "iss": "other"}, ErrTokenIssuer},
+		{"malformed exp", "HS256", Claims{"exp": "x", "aud": "api", "iss": "gee"}, ErrTokenMalformed},
+		{"malformed nbf", "HS256", Claims{"nbf": true, "aud": "api", "iss": "gee"}, ErrTokenMalformed},
+	}
+	for _, tt := range tests {
+		_, err := ParseJWT(signTest(t, tt.alg, tt.claims), conf)
+		if err != tt.want {
+			t.Errorf("%s: err = %v, want %v", tt.name, err, tt.want)
+		}
+	}
+}
+
+func TestParseJWTLeeway(t *testing.T) {
+	token := signTest(t, "HS256", Claims{"exp": float64(time.Now().Add(-time.Second).Unix())})
+	if _, err := ParseJWT(token, JWTConfig{Secret: testSecret, Leeway: time.Minute}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestParseJWTRejectsForgedTokens(t *testing.T) {
+	token := signTest(t, "HS256", Claims{"sub": "u1"})
+	parts := strings.Split(token, ".")
+
+	if _, err := ParseJWT(token, JWTConfig{Secret: []byte("other")}); err != ErrTokenSignature {
+		t.Fatalf("wrong secret: err = %v", err)
+	}
+	if _, err := ParseJWT(token, JWTConfig{Secret: testSecret, Algorithms: []string{"HS512"}}); err != ErrTokenAlgorithm {
+		t.Fatalf("disallowed algorithm: err = %v", err)
+	}
+	none := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"none"}`)) + "." + parts[1] + "."
+	if _, err := ParseJWT(none, JWTConfig{Secret: testSecret}); err != ErrTokenAlgorithm {
+		t.Fatalf("alg none: err = %v", err)
+	}
+	if _, err := ParseJWT(parts[0]+"."+parts[1], JWTConfig{Secret: testSecret}); err != ErrTokenMalformed {
+		t.Fatalf("two segments: err = %v", err)
+	}
+}
+
+func TestJWTEmptySecret(t *testing.T) {
+	if _, err := ParseJWT(signTest(t, "HS256", Claims{}), JWTConfig{}); err != ErrTokenSecret {
+		t.Fatalf("ParseJWT err = %v, want ErrTokenSecret", err)
+	}
+	if _, err := SignJWT("HS256", Claims{}, nil); err != ErrTokenSecret {
+		t.Fatalf("SignJWT err = %v, want ErrTokenSecret", err)
+	}
+	defer func() {
+		if recover() == nil {
+			t.Fatal("JWT with an empty secret did not panic")
+		}
+	}()
+	JWT(JWTConfig{})
+}
+
+func TestJWTMiddleware(t *testing.T) {
+	r := gee.New()
+	r.Use(JWT(JWTConfig{Secret: testSecret}))
+	r.GET("/", func(c *gee.Context) {
+		claims, _ := ClaimsFromContext(c)
+		user, _ := c.Get(AuthUserKey)
+		c.String(http.StatusOK, "%v %v", user, claims["role"])
+	})
+
+	token := signTest(t, "HS256", Claims{"sub": "u1", "role": "admin"})
+	w := performRequest(r, http.MethodGet, "/", nil, "Authorization", "Bearer "+token)
+	if w.Code != http.StatusOK || w.Body.String() != "u1 admin" {
+		t.Fatalf("got %d %q", w.Code, w.Body.String())
+	}
+	w = performRequest(r, http.MethodGet, "/", nil, "Authorization", "Bearer "+token+"x")
+	if w.Code != http.StatusUnauthorized || !strings.Contains(w.Header().Get("WWW-Authenticate"), "invalid_token") {
+		t.Fatalf("got %d %q", w.Code, w.Header().Get("WWW-Authenticate"))
+	}
+}
@@ -0,0 +1,27 @@
+package middleware
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"Gee/gee"
+)
+
+func TestMain(m *testing.M) {
+	gee.SetMode(gee.TestMode)
+	os.Exit(m.Run())
+}
+
+// performRequest 构造请求交给handler处理，headers依次为请求头的名称和值。
+func performRequest(handler http.Handler, method, path string, body io.Reader, headers ...string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, body)
+	for i := 0; i+1 < len(headers); i += 2 {
+		req.Header.Add(headers[i], headers[i+1])
+	}
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	return w
+}